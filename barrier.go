@@ -1,6 +1,9 @@
 package dwcas
 
-import "code.hybscloud.com/dwcas/internal/arch"
+import (
+	"code.hybscloud.com/dwcas/internal/arch"
+	"code.hybscloud.com/dwcas/internal/hook"
+)
 
 // BarrierAcquire emits an acquire barrier.
 //
@@ -13,6 +16,9 @@ import "code.hybscloud.com/dwcas/internal/arch"
 //   - amd64: compiler barrier only (prevents compile-time reordering across the
 //     call). It is not an MFENCE and is not required for cache coherence.
 func BarrierAcquire() {
+	if hookBarrier(hook.FenceAcquire) {
+		return
+	}
 	arch.BarrierAcquire()
 }
 
@@ -27,6 +33,9 @@ func BarrierAcquire() {
 //   - amd64: compiler barrier only (prevents compile-time reordering across the
 //     call). It is not an MFENCE and is not required for cache coherence.
 func BarrierRelease() {
+	if hookBarrier(hook.FenceRelease) {
+		return
+	}
 	arch.BarrierRelease()
 }
 
@@ -41,5 +50,8 @@ func BarrierRelease() {
 //   - amd64: compiler barrier only (prevents compile-time reordering across the
 //     call). It is not an MFENCE and is not required for cache coherence.
 func BarrierFull() {
+	if hookBarrier(hook.FenceFull) {
+		return
+	}
 	arch.BarrierFull()
 }
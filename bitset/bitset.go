@@ -0,0 +1,234 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package bitset provides a fixed-size concurrent bitset stored in 16-byte
+// aligned [dwcas.Uint128] words, 128 bits per word.
+//
+// Bit i lives in word i/128: bits 0-63 of a word are Lo and bits 64-127 are
+// Hi, least significant first. Every update is a 128-bit CAS on one word, so
+// [Set.FindFirstClearAndSet] can claim a free bit atomically out of a whole
+// word at a time. Reads have acquire ordering and updates acq_rel ordering.
+//
+// Operations on different bits are atomic individually; [Set.Count] and the
+// iterators read words one at a time and are not snapshots of the whole set.
+package bitset
+
+import (
+	"iter"
+	"math/bits"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// WordBits is the number of bits per word.
+const WordBits = 128
+
+// Set is a concurrent bitset of fixed length.
+type Set struct {
+	words []dwcas.Uint128
+	n     int
+}
+
+// New returns a set of n clear bits.
+//
+// New panics if n is negative.
+func New(n int) *Set {
+	if n < 0 {
+		panic("bitset: New: negative length")
+	}
+	return &Set{words: dwcas.NewSlice((n + WordBits - 1) / WordBits), n: n}
+}
+
+// Len returns the number of bits.
+func (s *Set) Len() int {
+	return s.n
+}
+
+// Words returns the number of words.
+func (s *Set) Words() int {
+	return len(s.words)
+}
+
+// mask returns the word holding bit i and a Uint128 with only that bit set.
+func (s *Set) mask(i int) (*dwcas.Uint128, dwcas.Uint128) {
+	if uint(i) >= uint(s.n) {
+		panic("bitset: index out of range")
+	}
+	return &s.words[i/WordBits], dwcas.Uint128{Lo: 1}.Lsh(uint(i % WordBits))
+}
+
+// valid returns the mask of bits of word w that are within the set.
+func (s *Set) valid(w int) dwcas.Uint128 {
+	all := dwcas.Uint128{Lo: ^uint64(0), Hi: ^uint64(0)}
+	if rem := s.n - w*WordBits; rem < WordBits {
+		return all.Rsh(uint(WordBits - rem))
+	}
+	return all
+}
+
+// update applies f to word p in a CAS loop and returns the old value.
+func update(p *dwcas.Uint128, f func(dwcas.Uint128) dwcas.Uint128) dwcas.Uint128 {
+	old := p.Load()
+	for {
+		next := f(old)
+		if next == old {
+			return old
+		}
+		prev, ok := p.AcqRel(old, next)
+		if ok {
+			return old
+		}
+		old = prev
+	}
+}
+
+// Test reports whether bit i is set.
+func (s *Set) Test(i int) bool {
+	p, m := s.mask(i)
+	return p.Load().And(m) != dwcas.Uint128{}
+}
+
+// Set sets bit i.
+func (s *Set) Set(i int) {
+	s.TestAndSet(i)
+}
+
+// Clear clears bit i.
+func (s *Set) Clear(i int) {
+	s.TestAndClear(i)
+}
+
+// TestAndSet sets bit i and reports whether it was already set.
+func (s *Set) TestAndSet(i int) bool {
+	p, m := s.mask(i)
+	old := update(p, func(w dwcas.Uint128) dwcas.Uint128 { return w.Or(m) })
+	return old.And(m) != dwcas.Uint128{}
+}
+
+// TestAndClear clears bit i and reports whether it was set.
+func (s *Set) TestAndClear(i int) bool {
+	p, m := s.mask(i)
+	notM := dwcas.Uint128{Lo: ^m.Lo, Hi: ^m.Hi}
+	old := update(p, func(w dwcas.Uint128) dwcas.Uint128 { return w.And(notM) })
+	return old.And(m) != dwcas.Uint128{}
+}
+
+// FindFirstClearAndSet atomically claims the lowest clear bit it finds,
+// setting it, and returns its index. ok is false if every bit was set when
+// its word was examined.
+func (s *Set) FindFirstClearAndSet() (i int, ok bool) {
+	return s.FindClearAndSetFrom(0)
+}
+
+// FindClearAndSetFrom is like [Set.FindFirstClearAndSet] but starts the
+// search at the word holding bit start and wraps around, so callers can
+// spread concurrent claims over the set.
+func (s *Set) FindClearAndSetFrom(start int) (i int, ok bool) {
+	nw := len(s.words)
+	if nw == 0 {
+		return 0, false
+	}
+	first := 0
+	if start > 0 {
+		first = (start / WordBits) % nw
+	}
+	for k := range nw {
+		w := (first + k) % nw
+		p := &s.words[w]
+		valid := s.valid(w)
+		old := p.Load()
+		for {
+			free := dwcas.Uint128{Lo: ^old.Lo, Hi: ^old.Hi}.And(valid)
+			if free == (dwcas.Uint128{}) {
+				break
+			}
+			b := trailingZeros(free)
+			prev, swapped := p.AcqRel(old, old.Or(dwcas.Uint128{Lo: 1}.Lsh(uint(b))))
+			if swapped {
+				return w*WordBits + b, true
+			}
+			old = prev
+		}
+	}
+	return 0, false
+}
+
+func trailingZeros(x dwcas.Uint128) int {
+	if x.Lo != 0 {
+		return bits.TrailingZeros64(x.Lo)
+	}
+	return 64 + bits.TrailingZeros64(x.Hi)
+}
+
+// Word returns word w.
+func (s *Set) Word(w int) dwcas.Uint128 {
+	return s.words[w].Load()
+}
+
+// CompareAndSwapWord replaces word w with new if it holds old, and reports
+// whether it did. Bits of new beyond Len must be clear.
+func (s *Set) CompareAndSwapWord(w int, old, new dwcas.Uint128) bool {
+	if new.And(s.valid(w)) != new {
+		panic("bitset: CompareAndSwapWord: bits beyond Len")
+	}
+	_, ok := s.words[w].AcqRel(old, new)
+	return ok
+}
+
+// Count returns the number of set bits.
+func (s *Set) Count() int {
+	n := 0
+	for w := range s.words {
+		n += s.words[w].Load().OnesCount()
+	}
+	return n
+}
+
+// All returns an iterator over the indices of set bits in increasing order.
+// Each word is loaded once, when the iteration reaches it.
+func (s *Set) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for w := range s.words {
+			x := s.words[w].Load()
+			for x != (dwcas.Uint128{}) {
+				b := trailingZeros(x)
+				if !yield(w*WordBits + b) {
+					return
+				}
+				x = x.And(x.Sub(dwcas.Uint128{Lo: 1})) // clear lowest set bit
+			}
+		}
+	}
+}
+
+// NextSet returns the index of the first set bit at or after i.
+func (s *Set) NextSet(i int) (int, bool) {
+	return s.next(i, false)
+}
+
+// NextClear returns the index of the first clear bit at or after i.
+func (s *Set) NextClear(i int) (int, bool) {
+	return s.next(i, true)
+}
+
+func (s *Set) next(i int, clear bool) (int, bool) {
+	if i < 0 {
+		i = 0
+	}
+	for w := i / WordBits; w < len(s.words) && i < s.n; w++ {
+		x := s.words[w].Load()
+		if clear {
+			x = dwcas.Uint128{Lo: ^x.Lo, Hi: ^x.Hi}
+		}
+		x = x.And(s.valid(w))
+		if w == i/WordBits {
+			// Drop the bits below i.
+			x = x.Rsh(uint(i % WordBits)).Lsh(uint(i % WordBits))
+		}
+		if x != (dwcas.Uint128{}) {
+			return w*WordBits + trailingZeros(x), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,176 @@
+package bitset_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/bitset"
+)
+
+func TestSet_Sequential(t *testing.T) {
+	s := bitset.New(300)
+	if s.Len() != 300 || s.Words() != 3 {
+		t.Fatalf("Len/Words: got %d/%d", s.Len(), s.Words())
+	}
+	for _, i := range []int{0, 63, 64, 127, 128, 299} {
+		if s.TestAndSet(i) {
+			t.Fatalf("bit %d set before TestAndSet", i)
+		}
+		if !s.Test(i) || !s.TestAndSet(i) {
+			t.Fatalf("bit %d not set after TestAndSet", i)
+		}
+	}
+	if got := slices.Collect(s.All()); !slices.Equal(got, []int{0, 63, 64, 127, 128, 299}) {
+		t.Fatalf("All: got %v", got)
+	}
+	if s.Count() != 6 {
+		t.Fatalf("Count: got %d", s.Count())
+	}
+	if w := s.Word(0); w != (dwcas.Uint128{Lo: 1 | 1<<63, Hi: 1 | 1<<63}) {
+		t.Fatalf("Word(0): got %#x", w)
+	}
+	s.Clear(63)
+	if s.Test(63) || !s.TestAndClear(64) || s.TestAndClear(64) {
+		t.Fatalf("Clear/TestAndClear misbehaved")
+	}
+	if i, ok := s.NextSet(1); !ok || i != 127 {
+		t.Fatalf("NextSet(1): got (%d, %v)", i, ok)
+	}
+	if i, ok := s.NextClear(127); !ok || i != 129 {
+		t.Fatalf("NextClear(127): got (%d, %v)", i, ok)
+	}
+	if _, ok := s.NextSet(300); ok {
+		t.Fatalf("NextSet past the end succeeded")
+	}
+	s.Set(5)
+	if i, ok := s.FindFirstClearAndSet(); !ok || i != 1 {
+		t.Fatalf("FindFirstClearAndSet: got (%d, %v)", i, ok)
+	}
+}
+
+func TestSet_FindRespectsLength(t *testing.T) {
+	s := bitset.New(130)
+	for want := range 130 {
+		i, ok := s.FindFirstClearAndSet()
+		if !ok || i != want {
+			t.Fatalf("claim %d: got (%d, %v)", want, i, ok)
+		}
+	}
+	if i, ok := s.FindFirstClearAndSet(); ok {
+		t.Fatalf("claimed bit %d of a full set", i)
+	}
+	if _, ok := s.NextClear(0); ok {
+		t.Fatalf("NextClear found a bit beyond Len")
+	}
+	s.Clear(3)
+	if i, ok := s.FindClearAndSetFrom(129); !ok || i != 3 {
+		t.Fatalf("FindClearAndSetFrom wraps: got (%d, %v)", i, ok)
+	}
+	if s.CompareAndSwapWord(1, s.Word(1), dwcas.Uint128{Lo: 3}) != true {
+		t.Fatalf("CompareAndSwapWord failed")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("CompareAndSwapWord accepted bits beyond Len")
+			}
+		}()
+		s.CompareAndSwapWord(1, s.Word(1), dwcas.Uint128{Lo: 4})
+	}()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Test(130) did not panic")
+			}
+		}()
+		s.Test(130)
+	}()
+}
+
+// TestSet_ConcurrentClaims has workers claim and release bits; no bit may be
+// held by two workers at once, and every bit is claimed exactly once when
+// workers only claim.
+func TestSet_ConcurrentClaims(t *testing.T) {
+	const (
+		n       = 1000
+		workers = 8
+	)
+	s := bitset.New(n)
+	owner := make([]int32, n)
+	var mu sync.Mutex
+	var claimed []int
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := range workers {
+		go func() {
+			defer wg.Done()
+			var mine []int
+			for {
+				i, ok := s.FindClearAndSetFrom(w * 128)
+				if !ok {
+					break
+				}
+				mine = append(mine, i)
+			}
+			mu.Lock()
+			claimed = append(claimed, mine...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	slices.Sort(claimed)
+	if len(claimed) != n {
+		t.Fatalf("claimed %d bits, want %d", len(claimed), n)
+	}
+	for i, b := range claimed {
+		if b != i {
+			t.Fatalf("bit %d claimed twice or bit %d never", b, i)
+		}
+	}
+	if s.Count() != n {
+		t.Fatalf("Count: got %d, want %d", s.Count(), n)
+	}
+
+	// Churn: claim, verify exclusive ownership, release.
+	for i := range n {
+		s.Clear(i)
+	}
+	iters := 5000
+	if testing.Short() {
+		iters = 500
+	}
+	wg.Add(workers)
+	for w := range workers {
+		go func() {
+			defer wg.Done()
+			for range iters {
+				i, ok := s.FindFirstClearAndSet()
+				if !ok {
+					continue
+				}
+				mu.Lock()
+				if owner[i] != 0 {
+					mu.Unlock()
+					t.Errorf("bit %d claimed by %d and %d", i, owner[i]-1, w)
+					return
+				}
+				owner[i] = int32(w + 1)
+				mu.Unlock()
+
+				mu.Lock()
+				owner[i] = 0
+				mu.Unlock()
+				if !s.TestAndClear(i) {
+					t.Errorf("bit %d was cleared by someone else", i)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if s.Count() != 0 {
+		t.Fatalf("Count after churn: got %d", s.Count())
+	}
+}
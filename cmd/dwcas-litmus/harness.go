@@ -0,0 +1,173 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// cellStride keeps every cell on its own cache line pair.
+const cellStride = 128
+
+// spinBarrier is a reusable barrier for a fixed number of goroutines. Spinning
+// keeps the threads of a round starting as close together as possible.
+type spinBarrier struct {
+	n     int32
+	count atomic.Int32
+	gen   atomic.Uint32
+}
+
+func (b *spinBarrier) wait() {
+	g := b.gen.Load()
+	if b.count.Add(1) == b.n {
+		b.count.Store(0)
+		b.gen.Add(1)
+		return
+	}
+	for i := 1; b.gen.Load() == g; i++ {
+		if i%1024 == 0 {
+			runtime.Gosched()
+		}
+	}
+}
+
+// result is the outcome histogram of one shape and variant.
+type result struct {
+	shape     string
+	variant   variant
+	rounds    int
+	counts    map[string]uint64
+	forbidden string // outcome key the variant excludes, or ""
+}
+
+// violations returns how often the excluded outcome was observed.
+func (r *result) violations() uint64 {
+	if r.forbidden == "" {
+		return 0
+	}
+	return r.counts[r.forbidden]
+}
+
+func outcomeKey(regs []uint64) string {
+	var b strings.Builder
+	for i, v := range regs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString("r")
+		b.WriteString(strconv.Itoa(i))
+		b.WriteByte('=')
+		b.WriteString(strconv.FormatUint(v, 10))
+	}
+	return b.String()
+}
+
+// forbiddenKey finds the outcome excluded by v among register values 0..2.
+func forbiddenKey(s shape, v variant) string {
+	if !s.forbids(v) {
+		return ""
+	}
+	regs := make([]uint64, s.regs)
+	var search func(i int) string
+	search = func(i int) string {
+		if i == len(regs) {
+			if s.forbidden(regs) {
+				return outcomeKey(regs)
+			}
+			return ""
+		}
+		for v := uint64(0); v <= 2; v++ {
+			regs[i] = v
+			if k := search(i + 1); k != "" {
+				return k
+			}
+		}
+		return ""
+	}
+	return search(0)
+}
+
+// run executes rounds of s under v, one locked OS thread per shape thread.
+func run(s shape, v variant, rounds int) *result {
+	buf := make([]byte, s.cells*cellStride+31)
+	cells := make([]*dwcas.Uint128, s.cells)
+	off := 0
+	for i := range cells {
+		_, cells[i] = dwcas.PlaceAlignedUint128(buf, off)
+		off += cellStride
+	}
+
+	res := &result{
+		shape:     s.name,
+		variant:   v,
+		rounds:    rounds,
+		counts:    map[string]uint64{},
+		forbidden: forbiddenKey(s, v),
+	}
+	regs := make([]uint64, s.regs)
+	e := env{v: v}
+	bar := &spinBarrier{n: int32(len(s.threads))}
+
+	var wg sync.WaitGroup
+	wg.Add(len(s.threads))
+	for id, th := range s.threads {
+		go func() {
+			defer wg.Done()
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+			for range rounds {
+				bar.wait()
+				th(e, cells, regs)
+				bar.wait()
+				if id != 0 {
+					continue
+				}
+				// Thread 0 records and resets while the others wait at the
+				// next round's barrier.
+				if s.final != nil {
+					s.final(cells, regs)
+				}
+				res.counts[outcomeKey(regs)]++
+				for _, c := range cells {
+					*c = dwcas.Uint128{}
+				}
+				clear(regs)
+			}
+		}()
+	}
+	wg.Wait()
+	return res
+}
+
+func (r *result) write(w io.Writer) {
+	fmt.Fprintf(w, "%s/%s rounds=%d\n", r.shape, r.variant, r.rounds)
+	keys := make([]string, 0, len(r.counts)+1)
+	for k := range r.counts {
+		keys = append(keys, k)
+	}
+	if r.forbidden != "" && r.counts[r.forbidden] == 0 {
+		keys = append(keys, r.forbidden)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		mark := ""
+		if k == r.forbidden {
+			mark = "  forbidden"
+			if r.counts[k] > 0 {
+				mark += " (OBSERVED)"
+			}
+		}
+		fmt.Fprintf(w, "  %-24s %12d%s\n", k, r.counts[k], mark)
+	}
+}
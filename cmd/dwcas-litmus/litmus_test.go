@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+
+	"code.hybscloud.com/dwcas/internal/race"
+)
+
+func TestForbiddenKey(t *testing.T) {
+	tests := []struct {
+		shape   string
+		variant variant
+		want    string
+	}{
+		{"MP", variantPlain, ""},
+		{"MP", variantBarrier, "r0=1 r1=0"},
+		{"MP", variantCASRelease, ""},
+		{"MP", variantCASAcqRel, "r0=1 r1=0"},
+		{"LB", variantCASAcqRel, "r0=1 r1=1"},
+		{"IRIW", variantBarrier, "r0=1 r1=0 r2=1 r3=0"},
+		{"2+2W", variantCASAcqRel, "r0=1 r1=1"},
+		{"SB", variantCASAcqRel, "r0=0 r1=0"},
+	}
+	for _, tt := range tests {
+		s, ok := lookupShape(tt.shape)
+		if !ok {
+			t.Fatalf("unknown shape %q", tt.shape)
+		}
+		if got := forbiddenKey(s, tt.variant); got != tt.want {
+			t.Fatalf("%s/%s: got %q, want %q", tt.shape, tt.variant, got, tt.want)
+		}
+	}
+
+	sb, _ := lookupShape("SB")
+	want := "r0=0 r1=0"
+	if runtime.GOARCH == "amd64" {
+		want = ""
+	}
+	if got := forbiddenKey(sb, variantBarrier); got != want {
+		t.Fatalf("SB/barrier on %s: got %q, want %q", runtime.GOARCH, got, want)
+	}
+}
+
+func TestRun_AllShapesAndVariants(t *testing.T) {
+	const rounds = 64
+	for _, s := range shapes {
+		for _, v := range variants {
+			// Plain and barrier variants race by design.
+			if race.Enabled && (v == variantPlain || v == variantBarrier) {
+				continue
+			}
+			r := run(s, v, rounds)
+			var total uint64
+			for _, n := range r.counts {
+				total += n
+			}
+			if total != rounds {
+				t.Fatalf("%s/%s: recorded %d outcomes, want %d", s.name, v, total, rounds)
+			}
+			// CAS accesses are atomic read-modify-writes: acq_rel on every
+			// access leaves no room for the forbidden outcome on any backend.
+			if v == variantCASAcqRel && r.violations() != 0 {
+				t.Fatalf("%s/%s: forbidden outcome observed %d times", s.name, v, r.violations())
+			}
+		}
+	}
+}
+
+func TestResultWrite_MarksForbidden(t *testing.T) {
+	r := &result{
+		shape:     "MP",
+		variant:   variantBarrier,
+		rounds:    3,
+		counts:    map[string]uint64{"r0=0 r1=0": 2, "r0=1 r1=0": 1},
+		forbidden: "r0=1 r1=0",
+	}
+	var buf bytes.Buffer
+	r.write(&buf)
+	if !strings.Contains(buf.String(), "forbidden (OBSERVED)") {
+		t.Fatalf("missing forbidden marker:\n%s", buf.String())
+	}
+}
+
+func TestSelect_RejectsUnknown(t *testing.T) {
+	if _, err := selectShapes("MP,XX"); err == nil {
+		t.Fatalf("expected error for unknown shape")
+	}
+	if _, err := selectVariants("cas-seqcst"); err == nil {
+		t.Fatalf("expected error for unknown variant")
+	}
+	vs, err := selectVariants("plain, cas-acqrel")
+	if err != nil || len(vs) != 2 {
+		t.Fatalf("selectVariants: got %v, %v", vs, err)
+	}
+}
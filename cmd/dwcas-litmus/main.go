@@ -0,0 +1,132 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Command dwcas-litmus runs classic litmus shapes against the dwcas barriers and
+// CAS ordering variants on the current machine.
+//
+// Each shape (MP, SB, LB, IRIW, 2+2W) runs for many rounds, one locked OS thread
+// per shape thread, and the observed register outcomes are printed as a
+// histogram. Every shape is run under each access variant:
+//
+//   - plain: racy plain loads and stores, no ordering.
+//   - barrier: plain accesses separated by BarrierAcquire, BarrierRelease or
+//     BarrierFull, as the shape requires.
+//   - cas-relaxed, cas-acquire, cas-release, cas-acqrel: every access is a
+//     Uint128 compare-and-swap with that ordering. Loads write back the value
+//     they observed, so only the successful attempt orders them.
+//
+// An outcome is marked forbidden when the variant excludes it under the dwcas
+// ordering contract. Plain and single-sided variants exclude nothing, even where
+// the hardware happens to be stronger. The barriers are compiler barriers only on
+// amd64, so SB under the barrier variant is not forbidden there.
+//
+// The command exits with status 1 if a forbidden outcome was observed.
+//
+// Usage:
+//
+//	dwcas-litmus [-shape MP,SB] [-variant barrier,cas-acqrel] [-rounds N]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+func main() {
+	shapeFlag := flag.String("shape", "all", "comma-separated shapes (MP, SB, LB, IRIW, 2+2W) or all")
+	variantFlag := flag.String("variant", "all", "comma-separated access variants or all")
+	rounds := flag.Int("rounds", 100000, "rounds per shape and variant")
+	list := flag.Bool("list", false, "list shapes and variants, then exit")
+	flag.Parse()
+
+	if *list {
+		for _, s := range shapes {
+			fmt.Printf("%-5s %s\n", s.name, s.doc)
+		}
+		for _, v := range variants {
+			fmt.Println(v)
+		}
+		return
+	}
+
+	selShapes, err := selectShapes(*shapeFlag)
+	if err != nil {
+		fatal(err)
+	}
+	selVariants, err := selectVariants(*variantFlag)
+	if err != nil {
+		fatal(err)
+	}
+	if *rounds <= 0 {
+		fatal(fmt.Errorf("rounds must be positive"))
+	}
+
+	for _, s := range selShapes {
+		if n := runtime.GOMAXPROCS(0); n < len(s.threads) {
+			fmt.Fprintf(os.Stderr, "dwcas-litmus: %s needs %d threads but GOMAXPROCS=%d; weak outcomes are unlikely\n",
+				s.name, len(s.threads), n)
+		}
+	}
+
+	fmt.Printf("GOOS=%s GOARCH=%s GOMAXPROCS=%d\n", runtime.GOOS, runtime.GOARCH, runtime.GOMAXPROCS(0))
+	var violated []string
+	for _, s := range selShapes {
+		for _, v := range selVariants {
+			r := run(s, v, *rounds)
+			r.write(os.Stdout)
+			if r.violations() > 0 {
+				violated = append(violated, s.name+"/"+string(v))
+			}
+		}
+	}
+	if len(violated) > 0 {
+		fmt.Printf("FORBIDDEN OUTCOMES OBSERVED: %s\n", strings.Join(violated, ", "))
+		os.Exit(1)
+	}
+}
+
+func selectShapes(arg string) ([]shape, error) {
+	if arg == "all" {
+		return shapes, nil
+	}
+	var out []shape
+	for _, name := range strings.Split(arg, ",") {
+		s, ok := lookupShape(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown shape %q", name)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func selectVariants(arg string) ([]variant, error) {
+	if arg == "all" {
+		return variants, nil
+	}
+	var out []variant
+	for _, name := range strings.Split(arg, ",") {
+		v := variant(strings.TrimSpace(name))
+		found := false
+		for _, known := range variants {
+			if v == known {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown variant %q", name)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "dwcas-litmus:", err)
+	os.Exit(2)
+}
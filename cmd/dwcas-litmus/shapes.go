@@ -0,0 +1,262 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"runtime"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// variant selects how a shape's memory accesses are ordered.
+type variant string
+
+const (
+	variantPlain      variant = "plain"
+	variantBarrier    variant = "barrier"
+	variantCASRelaxed variant = "cas-relaxed"
+	variantCASAcquire variant = "cas-acquire"
+	variantCASRelease variant = "cas-release"
+	variantCASAcqRel  variant = "cas-acqrel"
+)
+
+var variants = []variant{
+	variantPlain,
+	variantBarrier,
+	variantCASRelaxed,
+	variantCASAcquire,
+	variantCASRelease,
+	variantCASAcqRel,
+}
+
+// fence is the barrier placed between the two accesses of a thread in the
+// barrier variant.
+type fence uint8
+
+const (
+	fenceAcquire fence = iota
+	fenceRelease
+	fenceFull
+)
+
+// env is the per-variant access layer used by shape threads.
+type env struct {
+	v variant
+}
+
+// load reads c. CAS variants read with a compare-and-swap that writes back the
+// observed value; only a successful CAS carries the variant's ordering, so a
+// failed first attempt is retried with the observed value.
+func (e env) load(c *dwcas.Uint128) uint64 {
+	switch e.v {
+	case variantPlain, variantBarrier:
+		return loadPlain(c)
+	}
+	old := dwcas.Uint128{}
+	for {
+		prev, ok := e.cas(c, old, old)
+		if ok {
+			return prev.Lo
+		}
+		old = prev
+	}
+}
+
+// store writes v to c.
+func (e env) store(c *dwcas.Uint128, v uint64) {
+	switch e.v {
+	case variantPlain, variantBarrier:
+		storePlain(c, v)
+		return
+	}
+	old := dwcas.Uint128{}
+	for {
+		prev, ok := e.cas(c, old, dwcas.Uint128{Lo: v})
+		if ok {
+			return
+		}
+		old = prev
+	}
+}
+
+func (e env) cas(c *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool) {
+	switch e.v {
+	case variantCASAcquire:
+		return c.Acquire(old, new)
+	case variantCASRelease:
+		return c.Release(old, new)
+	case variantCASAcqRel:
+		return c.AcqRel(old, new)
+	default:
+		return c.Relaxed(old, new)
+	}
+}
+
+// between orders the two accesses of a thread in the barrier variant.
+func (e env) between(f fence) {
+	if e.v != variantBarrier {
+		return
+	}
+	switch f {
+	case fenceAcquire:
+		dwcas.BarrierAcquire()
+	case fenceRelease:
+		dwcas.BarrierRelease()
+	default:
+		dwcas.BarrierFull()
+	}
+}
+
+// Plain accesses are deliberately racy: they are what the hardware reorders.
+//
+//go:noinline
+func loadPlain(c *dwcas.Uint128) uint64 { return c.Lo }
+
+//go:noinline
+func storePlain(c *dwcas.Uint128, v uint64) { c.Lo = v }
+
+// shape is a litmus test.
+type shape struct {
+	name    string
+	doc     string
+	cells   int
+	regs    int
+	threads []func(e env, x []*dwcas.Uint128, r []uint64)
+
+	// final copies final cell values into registers, for shapes whose outcome
+	// is the final state of memory.
+	final func(x []*dwcas.Uint128, r []uint64)
+
+	// forbidden reports whether registers r are an outcome that variant v
+	// excludes under the dwcas ordering contract.
+	forbidden func(r []uint64) bool
+	forbids   func(v variant) bool
+}
+
+// acqRelOnly is the forbids rule of shapes that need a synchronizing pair:
+// the barrier variant or acquire-release CAS on every access.
+func acqRelOnly(v variant) bool {
+	return v == variantBarrier || v == variantCASAcqRel
+}
+
+var shapes = []shape{
+	{
+		name:  "MP",
+		doc:   "message passing: x=1; y=1 || r0=y; r1=x",
+		cells: 2, regs: 2,
+		threads: []func(e env, x []*dwcas.Uint128, r []uint64){
+			func(e env, x []*dwcas.Uint128, _ []uint64) {
+				e.store(x[0], 1)
+				e.between(fenceRelease)
+				e.store(x[1], 1)
+			},
+			func(e env, x []*dwcas.Uint128, r []uint64) {
+				r[0] = e.load(x[1])
+				e.between(fenceAcquire)
+				r[1] = e.load(x[0])
+			},
+		},
+		forbidden: func(r []uint64) bool { return r[0] == 1 && r[1] == 0 },
+		forbids:   acqRelOnly,
+	},
+	{
+		name:  "SB",
+		doc:   "store buffering: x=1; r0=y || y=1; r1=x",
+		cells: 2, regs: 2,
+		threads: []func(e env, x []*dwcas.Uint128, r []uint64){
+			func(e env, x []*dwcas.Uint128, r []uint64) {
+				e.store(x[0], 1)
+				e.between(fenceFull)
+				r[0] = e.load(x[1])
+			},
+			func(e env, x []*dwcas.Uint128, r []uint64) {
+				e.store(x[1], 1)
+				e.between(fenceFull)
+				r[1] = e.load(x[0])
+			},
+		},
+		forbidden: func(r []uint64) bool { return r[0] == 0 && r[1] == 0 },
+		forbids: func(v variant) bool {
+			// amd64 barriers are compiler barriers only and TSO lets a
+			// store pass a later load, so SB is allowed there.
+			if v == variantBarrier && runtime.GOARCH == "amd64" {
+				return false
+			}
+			return acqRelOnly(v)
+		},
+	},
+	{
+		name:  "LB",
+		doc:   "load buffering: r0=x; y=1 || r1=y; x=1",
+		cells: 2, regs: 2,
+		threads: []func(e env, x []*dwcas.Uint128, r []uint64){
+			func(e env, x []*dwcas.Uint128, r []uint64) {
+				r[0] = e.load(x[0])
+				e.between(fenceFull)
+				e.store(x[1], 1)
+			},
+			func(e env, x []*dwcas.Uint128, r []uint64) {
+				r[1] = e.load(x[1])
+				e.between(fenceFull)
+				e.store(x[0], 1)
+			},
+		},
+		forbidden: func(r []uint64) bool { return r[0] == 1 && r[1] == 1 },
+		forbids:   acqRelOnly,
+	},
+	{
+		name:  "IRIW",
+		doc:   "independent reads of independent writes: x=1 || y=1 || r0=x; r1=y || r2=y; r3=x",
+		cells: 2, regs: 4,
+		threads: []func(e env, x []*dwcas.Uint128, r []uint64){
+			func(e env, x []*dwcas.Uint128, _ []uint64) { e.store(x[0], 1) },
+			func(e env, x []*dwcas.Uint128, _ []uint64) { e.store(x[1], 1) },
+			func(e env, x []*dwcas.Uint128, r []uint64) {
+				r[0] = e.load(x[0])
+				e.between(fenceFull)
+				r[1] = e.load(x[1])
+			},
+			func(e env, x []*dwcas.Uint128, r []uint64) {
+				r[2] = e.load(x[1])
+				e.between(fenceFull)
+				r[3] = e.load(x[0])
+			},
+		},
+		forbidden: func(r []uint64) bool { return r[0] == 1 && r[1] == 0 && r[2] == 1 && r[3] == 0 },
+		forbids:   acqRelOnly,
+	},
+	{
+		name:  "2+2W",
+		doc:   "two plus two writes: x=1; y=2 || y=1; x=2; final (x, y)",
+		cells: 2, regs: 2,
+		threads: []func(e env, x []*dwcas.Uint128, r []uint64){
+			func(e env, x []*dwcas.Uint128, _ []uint64) {
+				e.store(x[0], 1)
+				e.between(fenceFull)
+				e.store(x[1], 2)
+			},
+			func(e env, x []*dwcas.Uint128, _ []uint64) {
+				e.store(x[1], 1)
+				e.between(fenceFull)
+				e.store(x[0], 2)
+			},
+		},
+		final: func(x []*dwcas.Uint128, r []uint64) {
+			r[0] = x[0].Lo
+			r[1] = x[1].Lo
+		},
+		forbidden: func(r []uint64) bool { return r[0] == 1 && r[1] == 1 },
+		forbids:   acqRelOnly,
+	},
+}
+
+func lookupShape(name string) (shape, bool) {
+	for _, s := range shapes {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return shape{}, false
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpand_CrossProduct(t *testing.T) {
+	got, err := expand("1,2", "shared,padded", "acqrel,mutex", "0,50,100", "none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2*2*2*3 {
+		t.Fatalf("scenarios: got %d, want 24", len(got))
+	}
+	if got[0] != (scenario{Goroutines: 1, Layout: "shared", Order: "acqrel", ReadPct: 0, Backoff: "none"}) {
+		t.Fatalf("first scenario: got %+v", got[0])
+	}
+}
+
+func TestExpand_RejectsInvalid(t *testing.T) {
+	cases := [][5]string{
+		{"0", "shared", "acqrel", "0", "none"},
+		{"1", "striped", "acqrel", "0", "none"},
+		{"1", "shared", "seqcst", "0", "none"},
+		{"1", "shared", "acqrel", "101", "none"},
+		{"1", "shared", "acqrel", "0", "sleep"},
+		{"x", "shared", "acqrel", "0", "none"},
+	}
+	for _, c := range cases {
+		if _, err := expand(c[0], c[1], c[2], c[3], c[4]); err == nil {
+			t.Fatalf("expand(%q) should fail", c)
+		}
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	samples := make([]int64, 1000)
+	for i := range samples {
+		samples[i] = int64(1000 - i)
+	}
+	l := percentiles(samples)
+	if l.P50 != 500 || l.P90 != 900 || l.P99 != 990 || l.P999 != 999 || l.Max != 1000 {
+		t.Fatalf("unexpected percentiles: %+v", l)
+	}
+	if (percentiles(nil) != latency{}) {
+		t.Fatalf("empty samples must yield zero percentiles")
+	}
+}
+
+func TestRun_CountsAndReport(t *testing.T) {
+	scenarios, err := expand("1,4", "shared,padded", "relaxed,acqrel,mutex", "0,90", "none,exp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := newReport()
+	for _, s := range scenarios {
+		r := run(s, 5*time.Millisecond, 8)
+		if r.Ops == 0 || r.Reads+r.Writes != r.Ops {
+			t.Fatalf("%v: inconsistent counts %+v", s, r)
+		}
+		if s.Order == "mutex" && r.CASAttempts != 0 {
+			t.Fatalf("%v: mutex baseline reported CAS attempts", s)
+		}
+		if s.Order != "mutex" && r.CASAttempts < r.Writes {
+			t.Fatalf("%v: fewer CAS attempts (%d) than writes (%d)", s, r.CASAttempts, r.Writes)
+		}
+		if r.FailureRatio < 0 || r.FailureRatio >= 1 {
+			t.Fatalf("%v: failure ratio out of range: %f", s, r.FailureRatio)
+		}
+		rep.Results = append(rep.Results, r)
+	}
+
+	var buf bytes.Buffer
+	if err := rep.writeJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var back report
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Results) != len(rep.Results) || back.Results[3].Scenario != rep.Results[3].Scenario {
+		t.Fatalf("JSON round trip mismatch")
+	}
+
+	buf.Reset()
+	if err := rep.writeText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != len(rep.Results)+3 {
+		t.Fatalf("text report has %d lines, want %d", n, len(rep.Results)+3)
+	}
+}
@@ -0,0 +1,144 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Command dwcasbench measures dwcas compare-and-swap under configurable
+// contention.
+//
+// Every combination of the selected goroutine counts, cell layouts, orderings,
+// read percentages and backoff policies is one scenario. Each goroutine runs a
+// mix of reads (a CAS that writes back its expected value) and writes (a
+// versioned bump of both words in a CAS loop) for the configured duration.
+//
+//   - layout shared: every goroutine operates on the same cell.
+//   - layout padded: every goroutine owns a cell on its own cache lines.
+//   - order mutex: a sync.Mutex-protected Uint128, as a baseline.
+//   - backoff: applied after each failed CAS (none, spin, yield, or exp for
+//     exponential spinning that falls back to yielding).
+//
+// For every scenario dwcasbench reports throughput, the ratio of failed CAS
+// attempts, and latency percentiles of a sample of operations, as a text table
+// or, with -json, as a JSON document.
+//
+// Usage:
+//
+//	dwcasbench [-goroutines 1,4,16] [-layout shared,padded] [-order acqrel,mutex]
+//	           [-reads 0,90] [-backoff none,exp] [-duration 1s] [-json]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	goroutines := flag.String("goroutines", "1,"+strconv.Itoa(runtime.GOMAXPROCS(0)), "comma-separated goroutine counts")
+	layout := flag.String("layout", "shared,padded", "comma-separated cell layouts: "+strings.Join(layouts, ", "))
+	order := flag.String("order", "relaxed,acqrel,mutex", "comma-separated orderings: "+strings.Join(orders, ", "))
+	reads := flag.String("reads", "0,90", "comma-separated read percentages (0-100)")
+	backoffFlag := flag.String("backoff", "none", "comma-separated backoff policies: "+strings.Join(backoffs, ", "))
+	duration := flag.Duration("duration", time.Second, "measurement time per scenario")
+	sample := flag.Int("sample", 64, "time every n-th operation for latency percentiles")
+	asJSON := flag.Bool("json", false, "write a JSON report instead of a text table")
+	flag.Parse()
+
+	scenarios, err := expand(*goroutines, *layout, *order, *reads, *backoffFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dwcasbench:", err)
+		os.Exit(2)
+	}
+	if *sample <= 0 || *duration <= 0 {
+		fmt.Fprintln(os.Stderr, "dwcasbench: -sample and -duration must be positive")
+		os.Exit(2)
+	}
+
+	rep := newReport()
+	for _, s := range scenarios {
+		r := run(s, *duration, *sample)
+		rep.Results = append(rep.Results, r)
+		if !*asJSON {
+			fmt.Fprintln(os.Stderr, "done:", s)
+		}
+	}
+	if *asJSON {
+		err = rep.writeJSON(os.Stdout)
+	} else {
+		err = rep.writeText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dwcasbench:", err)
+		os.Exit(1)
+	}
+}
+
+// expand parses the comma-separated flag values into the cross product of
+// scenarios.
+func expand(goroutines, layout, order, reads, backoff string) ([]scenario, error) {
+	gs, err := parseInts(goroutines, 1, 1<<16)
+	if err != nil {
+		return nil, fmt.Errorf("-goroutines: %w", err)
+	}
+	rs, err := parseInts(reads, 0, 100)
+	if err != nil {
+		return nil, fmt.Errorf("-reads: %w", err)
+	}
+	ls, err := parseWords(layout, layouts)
+	if err != nil {
+		return nil, fmt.Errorf("-layout: %w", err)
+	}
+	ords, err := parseWords(order, orders)
+	if err != nil {
+		return nil, fmt.Errorf("-order: %w", err)
+	}
+	bs, err := parseWords(backoff, backoffs)
+	if err != nil {
+		return nil, fmt.Errorf("-backoff: %w", err)
+	}
+
+	var out []scenario
+	for _, g := range gs {
+		for _, l := range ls {
+			for _, o := range ords {
+				for _, r := range rs {
+					for _, b := range bs {
+						out = append(out, scenario{Goroutines: g, Layout: l, Order: o, ReadPct: r, Backoff: b})
+					}
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseInts(arg string, lo, hi int) ([]int, error) {
+	var out []int
+	for _, f := range strings.Split(arg, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		if v < lo || v > hi {
+			return nil, fmt.Errorf("%d out of range [%d, %d]", v, lo, hi)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func parseWords(arg string, known []string) ([]string, error) {
+	var out []string
+	for _, f := range strings.Split(arg, ",") {
+		w := strings.TrimSpace(f)
+		if !slices.Contains(known, w) {
+			return nil, fmt.Errorf("unknown value %q", w)
+		}
+		out = append(out, w)
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+)
+
+// report is the complete output of a dwcasbench run.
+type report struct {
+	GOOS       string   `json:"goos"`
+	GOARCH     string   `json:"goarch"`
+	GOMAXPROCS int      `json:"gomaxprocs"`
+	NumCPU     int      `json:"num_cpu"`
+	GoVersion  string   `json:"go_version"`
+	Results    []result `json:"results"`
+}
+
+func newReport() *report {
+	return &report{
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		GoVersion:  runtime.Version(),
+	}
+}
+
+func (r *report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *report) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "%s/%s GOMAXPROCS=%d NumCPU=%d %s\n\n", r.GOOS, r.GOARCH, r.GOMAXPROCS, r.NumCPU, r.GoVersion)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "goroutines\tlayout\torder\treads\tbackoff\tMops/s\tfail%\tp50\tp90\tp99\tp99.9\tmax\t")
+	for _, res := range r.Results {
+		s := res.Scenario
+		l := res.LatencyNs
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d%%\t%s\t%.2f\t%.2f\t%v\t%v\t%v\t%v\t%v\t\n",
+			s.Goroutines, s.Layout, s.Order, s.ReadPct, s.Backoff,
+			res.OpsPerSec/1e6, res.FailureRatio*100,
+			time.Duration(l.P50), time.Duration(l.P90), time.Duration(l.P99), time.Duration(l.P999), time.Duration(l.Max))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,305 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// cellStride keeps padded cells on separate cache line pairs.
+const cellStride = 128
+
+// scenario is one benchmark configuration.
+type scenario struct {
+	Goroutines int    `json:"goroutines"`
+	Layout     string `json:"layout"` // "shared" or "padded"
+	Order      string `json:"order"`  // CAS ordering, or "mutex" for the baseline
+	ReadPct    int    `json:"read_pct"`
+	Backoff    string `json:"backoff"` // "none", "spin", "yield" or "exp"
+}
+
+func (s scenario) String() string {
+	return fmt.Sprintf("g=%d %s %s r=%d%% %s", s.Goroutines, s.Layout, s.Order, s.ReadPct, s.Backoff)
+}
+
+var (
+	layouts  = []string{"shared", "padded"}
+	orders   = []string{"relaxed", "acquire", "release", "acqrel", "mutex"}
+	backoffs = []string{"none", "spin", "yield", "exp"}
+)
+
+// latency holds latency percentiles in nanoseconds.
+type latency struct {
+	P50  int64 `json:"p50"`
+	P90  int64 `json:"p90"`
+	P99  int64 `json:"p99"`
+	P999 int64 `json:"p999"`
+	Max  int64 `json:"max"`
+}
+
+// result is the measurement of one scenario.
+type result struct {
+	Scenario     scenario `json:"scenario"`
+	DurationNs   int64    `json:"duration_ns"`
+	Ops          uint64   `json:"ops"`
+	Reads        uint64   `json:"reads"`
+	Writes       uint64   `json:"writes"`
+	CASAttempts  uint64   `json:"cas_attempts"`
+	CASFailures  uint64   `json:"cas_failures"`
+	OpsPerSec    float64  `json:"ops_per_sec"`
+	FailureRatio float64  `json:"failure_ratio"`
+	LatencyNs    latency  `json:"latency_ns"`
+	Samples      int      `json:"latency_samples"`
+}
+
+// cell is the state a goroutine operates on. guess is the goroutine's last
+// observation of the cell; CAS cells start every operation from it.
+type cell interface {
+	read(guess *dwcas.Uint128)
+	write(guess *dwcas.Uint128, b backoff) (attempts, failures uint64)
+}
+
+type casCell struct {
+	p   *dwcas.Uint128
+	cas func(p *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool)
+}
+
+// read takes an atomic snapshot with a CAS that writes back its expected value.
+func (c *casCell) read(guess *dwcas.Uint128) {
+	*guess, _ = c.cas(c.p, *guess, *guess)
+}
+
+// write performs a versioned bump of both words. Every attempt that fails
+// because another goroutine moved the cell counts as a failure.
+func (c *casCell) write(guess *dwcas.Uint128, b backoff) (attempts, failures uint64) {
+	old := *guess
+	for n := 0; ; n++ {
+		attempts++
+		next := dwcas.Uint128{Lo: old.Lo + 1, Hi: old.Hi + 1}
+		prev, ok := c.cas(c.p, old, next)
+		if ok {
+			*guess = next
+			return attempts, failures
+		}
+		failures++
+		b.wait(n)
+		old = prev
+	}
+}
+
+type mutexCell struct {
+	mu sync.Mutex
+	v  dwcas.Uint128
+}
+
+func (c *mutexCell) read(guess *dwcas.Uint128) {
+	c.mu.Lock()
+	*guess = c.v
+	c.mu.Unlock()
+}
+
+func (c *mutexCell) write(guess *dwcas.Uint128, _ backoff) (attempts, failures uint64) {
+	c.mu.Lock()
+	c.v.Lo++
+	c.v.Hi++
+	*guess = c.v
+	c.mu.Unlock()
+	return 0, 0
+}
+
+func casFunc(order string) func(p *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool) {
+	switch order {
+	case "acquire":
+		return (*dwcas.Uint128).Acquire
+	case "release":
+		return (*dwcas.Uint128).Release
+	case "acqrel":
+		return (*dwcas.Uint128).AcqRel
+	default:
+		return (*dwcas.Uint128).Relaxed
+	}
+}
+
+// newCells returns one cell per goroutine; shared layouts return the same cell
+// n times.
+func newCells(s scenario) []cell {
+	cells := make([]cell, s.Goroutines)
+	distinct := 1
+	if s.Layout == "padded" {
+		distinct = s.Goroutines
+	}
+	if s.Order == "mutex" {
+		// Over-allocate so that padded mutex cells do not share cache lines.
+		type paddedMutex struct {
+			mutexCell
+			_ [cellStride]byte
+		}
+		ms := make([]paddedMutex, distinct)
+		for i := range cells {
+			cells[i] = &ms[i%distinct].mutexCell
+		}
+		return cells
+	}
+	buf := make([]byte, distinct*cellStride+31)
+	cas := casFunc(s.Order)
+	shared := make([]cell, distinct)
+	for i := range shared {
+		_, p := dwcas.PlaceAlignedUint128(buf, i*cellStride)
+		shared[i] = &casCell{p: p, cas: cas}
+	}
+	for i := range cells {
+		cells[i] = shared[i%distinct]
+	}
+	return cells
+}
+
+// backoff is the policy applied after a failed CAS.
+type backoff string
+
+func (b backoff) wait(retry int) {
+	switch b {
+	case "spin":
+		spin(32)
+	case "yield":
+		runtime.Gosched()
+	case "exp":
+		if retry >= 10 {
+			runtime.Gosched()
+			return
+		}
+		spin(1 << retry)
+	}
+}
+
+var spinSink atomic.Uint64
+
+//go:noinline
+func spin(n int) {
+	var x uint64
+	for i := 0; i < n; i++ {
+		x += uint64(i)
+	}
+	spinSink.Add(x & 0)
+}
+
+// xorshift is a per-goroutine generator for the read/write mix.
+type xorshift uint64
+
+func (x *xorshift) next() uint64 {
+	v := uint64(*x)
+	v ^= v << 13
+	v ^= v >> 7
+	v ^= v << 17
+	*x = xorshift(v)
+	return v
+}
+
+// run measures s for d, timing every sampleEvery-th operation.
+func run(s scenario, d time.Duration, sampleEvery int) result {
+	cells := newCells(s)
+	var stop atomic.Bool
+	// Each goroutine updates its counters on every operation; the padding
+	// keeps neighbours off its cache lines.
+	type counters struct {
+		ops, reads, writes, attempts, failures uint64
+		samples                                []int64
+		_                                      [cellStride]byte
+	}
+	per := make([]counters, s.Goroutines)
+
+	var start, ready sync.WaitGroup
+	start.Add(1)
+	ready.Add(s.Goroutines)
+	var wg sync.WaitGroup
+	wg.Add(s.Goroutines)
+	for g := 0; g < s.Goroutines; g++ {
+		go func() {
+			defer wg.Done()
+			c := cells[g]
+			rng := xorshift(uint64(g)*0x9e3779b97f4a7c15 | 1)
+			b := backoff(s.Backoff)
+			k := &per[g]
+			var guess dwcas.Uint128
+			ready.Done()
+			start.Wait()
+			for i := 1; ; i++ {
+				if i%256 == 0 && stop.Load() {
+					return
+				}
+				var t0 time.Time
+				timed := i%sampleEvery == 0
+				if timed {
+					t0 = time.Now()
+				}
+				if int(rng.next()%100) < s.ReadPct {
+					c.read(&guess)
+					k.reads++
+				} else {
+					a, f := c.write(&guess, b)
+					k.writes++
+					k.attempts += a
+					k.failures += f
+				}
+				if timed {
+					k.samples = append(k.samples, int64(time.Since(t0)))
+				}
+				k.ops++
+			}
+		}()
+	}
+	ready.Wait()
+	t0 := time.Now()
+	start.Done()
+	time.Sleep(d)
+	stop.Store(true)
+	wg.Wait()
+	elapsed := time.Since(t0)
+
+	r := result{Scenario: s, DurationNs: int64(elapsed)}
+	var samples []int64
+	for i := range per {
+		k := &per[i]
+		r.Ops += k.ops
+		r.Reads += k.reads
+		r.Writes += k.writes
+		r.CASAttempts += k.attempts
+		r.CASFailures += k.failures
+		samples = append(samples, k.samples...)
+	}
+	r.OpsPerSec = float64(r.Ops) / elapsed.Seconds()
+	if r.CASAttempts > 0 {
+		r.FailureRatio = float64(r.CASFailures) / float64(r.CASAttempts)
+	}
+	r.Samples = len(samples)
+	r.LatencyNs = percentiles(samples)
+	return r
+}
+
+// percentiles sorts samples and returns nearest-rank percentiles.
+func percentiles(samples []int64) latency {
+	if len(samples) == 0 {
+		return latency{}
+	}
+	slices.Sort(samples)
+	rank := func(p float64) int64 {
+		i := int(p*float64(len(samples))+0.5) - 1
+		i = max(0, min(i, len(samples)-1))
+		return samples[i]
+	}
+	return latency{
+		P50:  rank(0.50),
+		P90:  rank(0.90),
+		P99:  rank(0.99),
+		P999: rank(0.999),
+		Max:  samples[len(samples)-1],
+	}
+}
@@ -47,6 +47,62 @@
 // primitives. On arm64 they map to DMB ISH*; on amd64 they are compiler barriers
 // (not MFENCE).
 //
+// # Value arithmetic
+//
+// Value methods such as [Uint128.Add], [Uint128.Mul], [Uint128.QuoRem] and
+// [Uint128.Cmp] treat a Uint128 as the integer Hi<<64 | Lo, wrapping modulo
+// 2^128. They are not atomic; use them to compute the new value of a CAS loop.
+//
+// Because the cell types [Uint128] and [Int128] carry both kinds of method,
+// the plain arithmetic names (Add, Sub, Mul) always mean value arithmetic, and
+// an atomic read-modify-write on the cell is named for its result, as in
+// [Int128.AddAndFetch]. Wrapper types whose methods are all atomic, such as
+// [AtomicFloat64Pair], keep the plain names.
+//
+// Uint128 also formats as a decimal integer ([Uint128.String], [Uint128.Format]),
+// parses with [ParseUint128], and implements the encoding.Text*, encoding.Binary*
+// and JSON marshalling interfaces; the binary form is 16 bytes, big-endian.
+// [FromBig], [FromBytes16] and [FromAddr] and their inverses convert losslessly
+// to and from *big.Int, [16]byte and netip.Addr.
+//
+// # Signed integers
+//
+// [Int128] is a two's complement counterpart to Uint128 with the same layout
+// and alignment contract. Its atomic methods (Load, Store, Swap, CompareAndSwap,
+// AddAndFetch) use the Uint128 CAS; its value methods (Add, Sub, Mul, QuoRem and
+// others) provide signed arithmetic, comparison, formatting and text and JSON
+// encoding.
+//
+// # Floating-point pairs
+//
+// [AtomicFloat64Pair] and [AtomicComplex128] update two float64 values together
+// through one cell. Their CompareAndSwap and Add compare bit patterns rather
+// than floating-point values, so NaNs and signed zeros behave predictably.
+//
+// # Sequence locks
+//
+// [SeqValue] holds a value too large for one CAS behind a sequence lock built on
+// a Uint128 (sequence, writer id) word: writers exclude each other, readers take
+// optimistic snapshots and retry if a write overlapped.
+//
+// # Reader-writer spinlock
+//
+// [RWSpinLock] keeps reader count, writer flags and a fairness ticket in one
+// Uint128, so each lock transition is a single CAS and writers are served in
+// arrival order rather than starved by readers.
+//
+// # Load-linked / store-conditional
+//
+// [VersionedCell] offers LoadLinked and StoreConditional over a value and a
+// version counter in one cell, so algorithms written in LL/SC form can be
+// ported without ABA hazards.
+//
+// # UUIDs
+//
+// [AtomicUUID] stores an RFC 9562 [UUID] in an internally aligned cell and
+// offers Load, Store, Swap and CompareAndSwap; [NewV4] and [NewV7] generate
+// random and time-ordered UUIDs.
+//
 // # Alignment
 //
 // The address of a *Uint128 passed to these methods MUST be 16-byte aligned.
@@ -54,6 +110,8 @@
 //
 // Helpers:
 //   - [New] returns a heap-allocated 16-byte aligned *Uint128.
+//   - [NewSlice] returns a heap-allocated []Uint128 whose elements are all
+//     16-byte aligned.
 //   - [CanPlaceAlignedUint128] / [PlaceAlignedUint128] place a 16-byte aligned
 //     *Uint128 within a caller-provided byte buffer. The worst-case required
 //     remaining bytes from off is 31.
@@ -73,4 +131,11 @@
 //
 //   - default: LSE pair-CAS (CASP family; CASPAL semantics)
 //   - opt-in: `-tags=dwcas_llsc` (LL/SC via LDXP with STXP or STLXP)
+//
+// # Interleaving exploration
+//
+// Building with `-tags=dwcas_model` turns every CAS method and manual barrier into
+// a scheduling point of the deterministic explorer in package
+// code.hybscloud.com/dwcas/model. Outside an exploration the methods behave as
+// in normal builds.
 package dwcas
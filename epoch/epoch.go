@@ -0,0 +1,260 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package epoch provides epoch-based reclamation (EBR) for lock-free structures
+// whose nodes live in index-addressed pools.
+//
+// EBR is a lower-overhead alternative to package hazard: readers do not
+// publish every node they touch, they only pin the current global epoch for
+// the duration of an operation. An index retired in epoch e is freed once the
+// global epoch has reached e+2, because by then every participant has been
+// unpinned at least once since the index was unlinked.
+//
+//	p := d.Register()
+//	defer p.Unregister()
+//
+//	g := p.Pin()
+//	h := head.Load()
+//	// ... unlink the node at h with (*dwcas.Uint128).AcqRel ...
+//	g.Unpin()
+//	p.Retire(index)
+//
+// A participant that stays pinned stops the epoch from advancing and therefore
+// stops all reclamation. [Domain.Stalled] reports such participants.
+package epoch
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// DefaultBatch is the number of retired indices after which a participant tries
+// to advance the epoch and free its bags.
+const DefaultBatch = 64
+
+// Domain is a global epoch shared by the participants of one data structure.
+type Domain struct {
+	epoch atomic.Uint64
+	free  func(index uint64)
+	batch int
+
+	mu    sync.Mutex
+	parts atomic.Pointer[[]*Participant]
+}
+
+// Participant is a goroutine's membership in a Domain. It holds the
+// participant's pin state and its deferred-reclamation bags.
+//
+// A Participant must be used by one goroutine at a time.
+type Participant struct {
+	d  *Domain
+	id int
+
+	// state is epoch<<1|1 while pinned and 0 otherwise.
+	state    atomic.Uint64
+	pinnedAt atomic.Int64
+	inUse    bool // guarded by d.mu
+	depth    int
+
+	bags     [3][]uint64
+	bagEpoch [3]uint64
+	pending  int
+}
+
+// Guard is an active pin. Call [Guard.Unpin] exactly once.
+type Guard struct {
+	p *Participant
+}
+
+// Stall describes a participant that has been pinned for too long.
+type Stall struct {
+	// Participant is the ID of the pinned participant.
+	Participant int
+
+	// Epoch is the epoch the participant pinned.
+	Epoch uint64
+
+	// PinnedFor is how long the participant has been pinned.
+	PinnedFor time.Duration
+
+	// Blocking reports whether the participant currently prevents the global
+	// epoch from advancing.
+	Blocking bool
+}
+
+// NewDomain returns a domain that calls free with every retired index once it
+// is safe to reuse. batch is the bag size that triggers reclamation; zero means
+// DefaultBatch.
+//
+// NewDomain panics if free is nil or batch is negative.
+func NewDomain(free func(index uint64), batch int) *Domain {
+	if free == nil {
+		panic("epoch: NewDomain: nil free function")
+	}
+	if batch < 0 {
+		panic("epoch: NewDomain: negative batch")
+	}
+	if batch == 0 {
+		batch = DefaultBatch
+	}
+	d := &Domain{free: free, batch: batch}
+	d.parts.Store(&[]*Participant{})
+	return d
+}
+
+// Epoch returns the current global epoch.
+func (d *Domain) Epoch() uint64 {
+	return d.epoch.Load()
+}
+
+// Register returns a participant, reusing an unregistered one when possible.
+func (d *Domain) Register() *Participant {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	parts := *d.parts.Load()
+	for _, p := range parts {
+		if !p.inUse {
+			p.inUse = true
+			return p
+		}
+	}
+	p := &Participant{d: d, id: len(parts), inUse: true}
+	next := append(parts[:len(parts):len(parts)], p)
+	d.parts.Store(&next)
+	return p
+}
+
+// Unregister returns p to its domain. p must not be pinned. Indices still in
+// p's bags are freed by the next participant that reuses p.
+func (p *Participant) Unregister() {
+	if p.depth != 0 {
+		panic("epoch: Unregister while pinned")
+	}
+	p.collect(p.d.epoch.Load())
+	p.d.mu.Lock()
+	p.inUse = false
+	p.d.mu.Unlock()
+}
+
+// ID returns the participant's identifier within its domain.
+func (p *Participant) ID() int {
+	return p.id
+}
+
+// Pin pins the current global epoch. Pins nest: only the outermost Unpin
+// releases the epoch.
+func (p *Participant) Pin() Guard {
+	p.depth++
+	if p.depth > 1 {
+		return Guard{p: p}
+	}
+	p.pinnedAt.Store(time.Now().UnixNano())
+	e := p.d.epoch.Load()
+	for {
+		p.state.Store(e<<1 | 1)
+		// The pin must be visible before the pinned section reads shared
+		// state; this pairs with the full barrier in TryAdvance.
+		dwcas.BarrierFull()
+		cur := p.d.epoch.Load()
+		if cur == e {
+			return Guard{p: p}
+		}
+		e = cur
+	}
+}
+
+// Unpin ends the pin taken by [Participant.Pin].
+func (g Guard) Unpin() {
+	p := g.p
+	p.depth--
+	if p.depth == 0 {
+		p.state.Store(0)
+	}
+}
+
+// Retire defers freeing index until no pinned participant can still observe
+// it. The caller must already have unlinked index from the data structure.
+func (p *Participant) Retire(index uint64) {
+	e := p.d.epoch.Load()
+	p.collect(e)
+	slot := e % 3
+	p.bags[slot] = append(p.bags[slot], index)
+	p.bagEpoch[slot] = e
+	p.pending++
+	if p.pending >= p.d.batch {
+		p.d.TryAdvance()
+		p.collect(p.d.epoch.Load())
+	}
+}
+
+// Pending returns the number of indices retired by p and not yet freed.
+func (p *Participant) Pending() int {
+	return p.pending
+}
+
+// Flush tries to advance the epoch twice and frees every bag that became safe.
+// It returns the number of freed indices.
+func (p *Participant) Flush() int {
+	before := p.pending
+	p.d.TryAdvance()
+	p.d.TryAdvance()
+	p.collect(p.d.epoch.Load())
+	return before - p.pending
+}
+
+// collect frees the bags retired at least two epochs before e.
+func (p *Participant) collect(e uint64) {
+	for i := range p.bags {
+		if len(p.bags[i]) == 0 || p.bagEpoch[i]+2 > e {
+			continue
+		}
+		for _, idx := range p.bags[i] {
+			p.d.free(idx)
+		}
+		p.pending -= len(p.bags[i])
+		p.bags[i] = p.bags[i][:0]
+	}
+}
+
+// TryAdvance advances the global epoch if every pinned participant has
+// observed it. It reports whether the epoch moved past the value it observed.
+func (d *Domain) TryAdvance() bool {
+	e := d.epoch.Load()
+	// Retirements that preceded this call must be visible before pins are
+	// inspected; this pairs with the full barrier in Pin.
+	dwcas.BarrierFull()
+	for _, p := range *d.parts.Load() {
+		if s := p.state.Load(); s&1 == 1 && s>>1 != e {
+			return false
+		}
+	}
+	return d.epoch.CompareAndSwap(e, e+1) || d.epoch.Load() != e
+}
+
+// Stalled reports the participants that have been pinned for longer than after.
+func (d *Domain) Stalled(after time.Duration) []Stall {
+	e := d.epoch.Load()
+	now := time.Now().UnixNano()
+	var out []Stall
+	for _, p := range *d.parts.Load() {
+		s := p.state.Load()
+		if s&1 == 0 {
+			continue
+		}
+		pinnedFor := time.Duration(now - p.pinnedAt.Load())
+		if pinnedFor <= after {
+			continue
+		}
+		out = append(out, Stall{
+			Participant: p.id,
+			Epoch:       s >> 1,
+			PinnedFor:   pinnedFor,
+			Blocking:    s>>1 != e,
+		})
+	}
+	return out
+}
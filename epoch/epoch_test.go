@@ -0,0 +1,193 @@
+package epoch_test
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/epoch"
+)
+
+func TestRetire_WaitsForPinnedParticipant(t *testing.T) {
+	var freed []uint64
+	d := epoch.NewDomain(func(i uint64) { freed = append(freed, i) }, 1<<20)
+	reader := d.Register()
+	writer := d.Register()
+
+	g := reader.Pin()
+	writer.Retire(7)
+	for i := 0; i < 4; i++ {
+		writer.Flush()
+	}
+	if len(freed) != 0 {
+		t.Fatalf("index freed while a participant pinned the retiring epoch: %v", freed)
+	}
+	if d.TryAdvance() && d.TryAdvance() {
+		t.Fatalf("epoch advanced twice past a pinned participant")
+	}
+
+	g.Unpin()
+	if n := writer.Flush(); n != 1 || len(freed) != 1 || freed[0] != 7 {
+		t.Fatalf("Flush after Unpin freed %d (%v), want index 7", n, freed)
+	}
+	if writer.Pending() != 0 {
+		t.Fatalf("pending: got %d, want 0", writer.Pending())
+	}
+}
+
+func TestPin_Nests(t *testing.T) {
+	d := epoch.NewDomain(func(uint64) {}, 0)
+	p := d.Register()
+	q := d.Register()
+	outer := p.Pin()
+	inner := p.Pin()
+	inner.Unpin()
+
+	e := d.Epoch()
+	d.TryAdvance()
+	if d.TryAdvance() {
+		t.Fatalf("nested Unpin released the outer pin")
+	}
+	outer.Unpin()
+	if !d.TryAdvance() || d.Epoch() <= e+1 {
+		t.Fatalf("epoch did not advance after the outer Unpin")
+	}
+	q.Unregister()
+	p.Unregister()
+}
+
+func TestRegister_ReusesParticipants(t *testing.T) {
+	d := epoch.NewDomain(func(uint64) {}, 0)
+	a := d.Register()
+	b := d.Register()
+	id := a.ID()
+	a.Unregister()
+	if c := d.Register(); c.ID() != id {
+		t.Fatalf("Register did not reuse participant %d (got %d)", id, c.ID())
+	}
+	if b.ID() == id {
+		t.Fatalf("distinct participants share an ID")
+	}
+}
+
+func TestStalled_ReportsLongPins(t *testing.T) {
+	d := epoch.NewDomain(func(uint64) {}, 0)
+	p := d.Register()
+	d.Register()
+	g := p.Pin()
+	time.Sleep(20 * time.Millisecond)
+
+	stalls := d.Stalled(10 * time.Millisecond)
+	if len(stalls) != 1 || stalls[0].Participant != p.ID() || stalls[0].Blocking {
+		t.Fatalf("unexpected stalls before advance: %+v", stalls)
+	}
+	d.TryAdvance()
+	stalls = d.Stalled(10 * time.Millisecond)
+	if len(stalls) != 1 || !stalls[0].Blocking || stalls[0].PinnedFor < 10*time.Millisecond {
+		t.Fatalf("pinned participant should block the next epoch: %+v", stalls)
+	}
+	if len(d.Stalled(time.Hour)) != 0 {
+		t.Fatalf("no participant has been pinned for an hour")
+	}
+	g.Unpin()
+	if len(d.Stalled(0)) != 0 {
+		t.Fatalf("unpinned participants must not be reported")
+	}
+}
+
+type node struct {
+	next atomic.Uint64
+	gen  atomic.Uint64
+}
+
+func TestTreiberStack_PinnedNodesAreNeverRecycled(t *testing.T) {
+	const (
+		nodes   = 256
+		workers = 8
+	)
+	iters := 20000
+	if testing.Short() {
+		iters = 2000
+	}
+
+	pool := make([]node, nodes)
+	var mu sync.Mutex
+	var freeList []uint64
+	for i := uint64(0); i < nodes; i++ {
+		freeList = append(freeList, i)
+	}
+	d := epoch.NewDomain(func(i uint64) {
+		pool[i].gen.Add(1)
+		mu.Lock()
+		freeList = append(freeList, i)
+		mu.Unlock()
+	}, 16)
+	alloc := func() (uint64, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(freeList) == 0 {
+			return 0, false
+		}
+		i := freeList[len(freeList)-1]
+		freeList = freeList[:len(freeList)-1]
+		return i, true
+	}
+
+	head := dwcas.New(0, 0)
+	var recycled atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			p := d.Register()
+			defer p.Unregister()
+			for it := 0; it < iters; it++ {
+				if it%2 == 0 {
+					i, ok := alloc()
+					if !ok {
+						p.Flush()
+						continue
+					}
+					old := head.Load()
+					for {
+						pool[i].next.Store(old.Lo)
+						prev, ok := head.AcqRel(old, dwcas.Uint128{Lo: i + 1, Hi: old.Hi + 1})
+						if ok {
+							break
+						}
+						old = prev
+					}
+					continue
+				}
+				g := p.Pin()
+				h := head.Load()
+				for h.Lo != 0 {
+					i := h.Lo - 1
+					gen := pool[i].gen.Load()
+					next := pool[i].next.Load()
+					runtime.Gosched()
+					prev, ok := head.AcqRel(h, dwcas.Uint128{Lo: next, Hi: h.Hi + 1})
+					if pool[i].gen.Load() != gen {
+						recycled.Add(1)
+					}
+					if ok {
+						g.Unpin()
+						p.Retire(i)
+						g = p.Pin()
+						break
+					}
+					h = prev
+				}
+				g.Unpin()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := recycled.Load(); n != 0 {
+		t.Fatalf("%d nodes were recycled while a pinned reader held them", n)
+	}
+}
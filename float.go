@@ -0,0 +1,115 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dwcas
+
+import "math"
+
+// Float64Pair is two float64 values updated together, such as a running sum and
+// sum of squares.
+type Float64Pair struct {
+	X, Y float64
+}
+
+// bits returns the cell encoding of v: X's bits in Lo, Y's bits in Hi. This is
+// also the memory layout of a complex128, real part first.
+func (v Float64Pair) bits() Uint128 {
+	return Uint128{Lo: math.Float64bits(v.X), Hi: math.Float64bits(v.Y)}
+}
+
+func pairFromBits(u Uint128) Float64Pair {
+	return Float64Pair{X: math.Float64frombits(u.Lo), Y: math.Float64frombits(u.Hi)}
+}
+
+// AtomicFloat64Pair is a [Float64Pair] stored in a 16-byte aligned [Uint128]
+// cell, so both values are read and written by one 128-bit atomic.
+//
+// Comparisons are on bit patterns, not floating-point equality:
+// CompareAndSwap matches a NaN with a NaN of identical bits, and does not
+// match 0 with -0. Add retries only on a changed bit pattern, so it makes
+// progress when the stored value is NaN.
+//
+// The zero value holds (0, 0) and is ready to use. An AtomicFloat64Pair must
+// not be copied after first use. Load has acquire ordering; the other methods
+// have acq_rel ordering on success.
+type AtomicFloat64Pair struct {
+	c inlineCell
+}
+
+// Load returns the current pair.
+func (a *AtomicFloat64Pair) Load() Float64Pair {
+	return pairFromBits(a.c.ptr().Load())
+}
+
+// Store sets the pair to v.
+func (a *AtomicFloat64Pair) Store(v Float64Pair) {
+	a.c.swap(v.bits())
+}
+
+// Swap sets the pair to v and returns the previous pair.
+func (a *AtomicFloat64Pair) Swap(v Float64Pair) (old Float64Pair) {
+	return pairFromBits(a.c.swap(v.bits()))
+}
+
+// CompareAndSwap sets the pair to new if its bits equal those of old, and
+// reports whether it did.
+func (a *AtomicFloat64Pair) CompareAndSwap(old, new Float64Pair) (swapped bool) {
+	_, swapped = a.c.ptr().AcqRel(old.bits(), new.bits())
+	return swapped
+}
+
+// Add adds delta to both values together and returns the new pair.
+func (a *AtomicFloat64Pair) Add(delta Float64Pair) (new Float64Pair) {
+	return pairFromBits(a.c.update(func(cur Uint128) Uint128 {
+		v := pairFromBits(cur)
+		return Float64Pair{X: v.X + delta.X, Y: v.Y + delta.Y}.bits()
+	}))
+}
+
+// AtomicComplex128 is a complex128 stored in a 16-byte aligned [Uint128] cell,
+// with the real part in Lo and the imaginary part in Hi.
+//
+// It follows the comparison, ordering and copying rules of
+// [AtomicFloat64Pair]; the zero value holds 0.
+type AtomicComplex128 struct {
+	c inlineCell
+}
+
+func complexBits(v complex128) Uint128 {
+	return Float64Pair{X: real(v), Y: imag(v)}.bits()
+}
+
+func complexFromBits(u Uint128) complex128 {
+	v := pairFromBits(u)
+	return complex(v.X, v.Y)
+}
+
+// Load returns the current value.
+func (a *AtomicComplex128) Load() complex128 {
+	return complexFromBits(a.c.ptr().Load())
+}
+
+// Store sets the value to v.
+func (a *AtomicComplex128) Store(v complex128) {
+	a.c.swap(complexBits(v))
+}
+
+// Swap sets the value to v and returns the previous value.
+func (a *AtomicComplex128) Swap(v complex128) (old complex128) {
+	return complexFromBits(a.c.swap(complexBits(v)))
+}
+
+// CompareAndSwap sets the value to new if its bits equal those of old, and
+// reports whether it did.
+func (a *AtomicComplex128) CompareAndSwap(old, new complex128) (swapped bool) {
+	_, swapped = a.c.ptr().AcqRel(complexBits(old), complexBits(new))
+	return swapped
+}
+
+// Add adds delta and returns the new value.
+func (a *AtomicComplex128) Add(delta complex128) (new complex128) {
+	return complexFromBits(a.c.update(func(cur Uint128) Uint128 {
+		return complexBits(complexFromBits(cur) + delta)
+	}))
+}
@@ -0,0 +1,113 @@
+package dwcas_test
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+)
+
+func TestAtomicFloat64Pair(t *testing.T) {
+	var a dwcas.AtomicFloat64Pair
+	if a.Load() != (dwcas.Float64Pair{}) {
+		t.Fatalf("zero value: got %v", a.Load())
+	}
+	a.Store(dwcas.Float64Pair{X: 1.5, Y: -2})
+	if got := a.Add(dwcas.Float64Pair{X: 1, Y: 4}); got != (dwcas.Float64Pair{X: 2.5, Y: 2}) {
+		t.Fatalf("Add: got %v", got)
+	}
+	if old := a.Swap(dwcas.Float64Pair{X: 3}); old != (dwcas.Float64Pair{X: 2.5, Y: 2}) {
+		t.Fatalf("Swap: got %v", old)
+	}
+	if !a.CompareAndSwap(dwcas.Float64Pair{X: 3}, dwcas.Float64Pair{Y: 1}) || a.Load() != (dwcas.Float64Pair{Y: 1}) {
+		t.Fatalf("CompareAndSwap failed: %v", a.Load())
+	}
+}
+
+func TestAtomicFloat64Pair_BitwiseCompare(t *testing.T) {
+	var a dwcas.AtomicFloat64Pair
+	nan := math.NaN()
+	a.Store(dwcas.Float64Pair{X: nan, Y: 0})
+
+	// A NaN with the same bits matches even though NaN != NaN.
+	if !a.CompareAndSwap(dwcas.Float64Pair{X: nan, Y: 0}, dwcas.Float64Pair{X: nan, Y: 1}) {
+		t.Fatalf("CompareAndSwap did not match identical NaN bits")
+	}
+	other := math.Float64frombits(math.Float64bits(nan) ^ 1)
+	if a.CompareAndSwap(dwcas.Float64Pair{X: other, Y: 1}, dwcas.Float64Pair{}) {
+		t.Fatalf("CompareAndSwap matched a NaN with different bits")
+	}
+	// -0 == 0 as floats, but their bits differ.
+	if a.CompareAndSwap(dwcas.Float64Pair{X: nan, Y: math.Copysign(0, -1)}, dwcas.Float64Pair{}) {
+		t.Fatalf("CompareAndSwap matched -0 against 0")
+	}
+	a.Store(dwcas.Float64Pair{X: nan, Y: math.Copysign(1, -1)})
+	// Add must terminate although the stored value never equals itself.
+	got := a.Add(dwcas.Float64Pair{X: 1, Y: 1})
+	if !math.IsNaN(got.X) || got.Y != 0 {
+		t.Fatalf("Add with NaN: got %v", got)
+	}
+}
+
+func TestAtomicComplex128(t *testing.T) {
+	var a dwcas.AtomicComplex128
+	a.Store(1 + 2i)
+	if got := a.Add(3 - 1i); got != 4+1i || a.Load() != 4+1i {
+		t.Fatalf("Add: got %v", got)
+	}
+	if old := a.Swap(-1i); old != 4+1i {
+		t.Fatalf("Swap: got %v", old)
+	}
+	if a.CompareAndSwap(1i, 0) || !a.CompareAndSwap(-1i, 5) || a.Load() != 5 {
+		t.Fatalf("CompareAndSwap: got %v", a.Load())
+	}
+	nan := complex(math.NaN(), 0)
+	a.Store(nan)
+	if !a.CompareAndSwap(nan, 0) {
+		t.Fatalf("CompareAndSwap did not match identical NaN bits")
+	}
+}
+
+func TestAtomicFloat64Pair_ConcurrentAdd(t *testing.T) {
+	const workers = 8
+	iters := 5000
+	if testing.Short() {
+		iters = 500
+	}
+	var a dwcas.AtomicFloat64Pair
+	var stop atomic.Bool
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		// Every update adds (k, k*k) with k in {1, 2}, so any state
+		// reached through whole updates satisfies X <= Y <= 2X.
+		for !stop.Load() {
+			v := a.Load()
+			if v.Y < v.X || v.Y > 2*v.X {
+				t.Errorf("torn pair %v", v)
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			k := float64(w%2 + 1)
+			for i := 0; i < iters; i++ {
+				a.Add(dwcas.Float64Pair{X: k, Y: k * k})
+			}
+		}()
+	}
+	wg.Wait()
+	stop.Store(true)
+	readers.Wait()
+	n := float64(iters * workers / 2)
+	if got, want := a.Load(), (dwcas.Float64Pair{X: 3 * n, Y: 5 * n}); got != want {
+		t.Fatalf("concurrent Add: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,444 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package hashmap provides a concurrent open-addressing hash map from uint64
+// keys to uint64 values whose buckets are [dwcas.Uint128] cells.
+//
+// Each bucket holds the key in Lo and the value in Hi, so inserting, updating
+// and deleting an entry are each a single 128-bit CAS. Collisions are resolved
+// by linear probing. A key, once placed in a bucket, stays there for the
+// lifetime of that bucket array; deletion leaves a tombstone value behind.
+//
+// When a bucket array fills up, a larger one is allocated and goroutines that
+// touch the map cooperatively migrate buckets to it, chunk by chunk. Migrated
+// buckets are marked so that operations continue in the new array.
+//
+// Loads never wait. Updates do not block one another, except during a
+// migration: an update that reaches a migrated bucket waits until every chunk
+// has been copied, so a goroutine stalled in the middle of a chunk delays
+// writers until it resumes.
+//
+// Reserved values:
+//   - Key 0 marks an empty bucket and cannot be stored.
+//   - Values [Tombstone] and [Tombstone]-1 mark deleted and migrated buckets
+//     and cannot be stored.
+//
+// Store, LoadOrStore and Delete panic when given reserved values.
+package hashmap
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"code.hybscloud.com/dwcas"
+)
+
+const (
+	// Tombstone is the largest value; it and Tombstone-1 are reserved.
+	Tombstone = ^uint64(0)
+
+	// moved marks a bucket whose content now lives in the next bucket array.
+	moved = Tombstone - 1
+
+	minCapacity = 16
+
+	// chunk is the number of buckets a migrating goroutine claims at once.
+	chunk = 64
+
+	// sealed is set in table.live once the successor is being sized.
+	sealed = 1 << 62
+)
+
+// table is one bucket array.
+type table struct {
+	buckets []dwcas.Uint128
+	mask    uint64
+
+	used atomic.Int64 // buckets that have ever held a key
+	live atomic.Int64 // keys with a live value, plus sealed
+
+	next     atomic.Pointer[table]
+	claimed  atomic.Int64 // buckets claimed for migration
+	migrated atomic.Int64 // buckets migrated
+}
+
+func newTable(capacity int) *table {
+	n := minCapacity
+	for n < capacity {
+		n <<= 1
+	}
+	return &table{buckets: dwcas.NewSlice(n), mask: uint64(n - 1)}
+}
+
+// addKey accounts for a key about to be stored in an empty bucket of t. It
+// reports false, undoing the accounting, if that would fill more than half of
+// the buckets or if t is sealed.
+func (t *table) addKey() bool {
+	if t.used.Add(1)*2 > int64(len(t.buckets)) {
+		t.used.Add(-1)
+		return false
+	}
+	if !t.addLive() {
+		t.used.Add(-1)
+		return false
+	}
+	return true
+}
+
+// addLive accounts for a key about to get a live value in t. It reports false,
+// undoing the accounting, if t is sealed.
+func (t *table) addLive() bool {
+	if t.live.Add(1)&sealed != 0 {
+		t.live.Add(-1)
+		return false
+	}
+	return true
+}
+
+// seal stops t from gaining live keys and returns how many it may hold. Every
+// key that migration copies out of t is among them.
+func (t *table) seal() int {
+	return int(t.live.Or(sealed) &^ sealed)
+}
+
+// Map is a concurrent hash map from uint64 keys to uint64 values.
+//
+// The zero value is not usable; call [New].
+type Map struct {
+	cur   atomic.Pointer[table] // oldest bucket array still in use
+	count atomic.Int64
+}
+
+// New returns a map sized for about capacity entries.
+func New(capacity int) *Map {
+	m := &Map{}
+	m.cur.Store(newTable(capacity * 2))
+	return m
+}
+
+// Len returns the number of entries. Concurrent updates may or may not be
+// reflected.
+func (m *Map) Len() int {
+	return int(m.count.Load())
+}
+
+func hash(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
+
+func checkKey(k uint64) {
+	if k == 0 {
+		panic("hashmap: key 0 is reserved")
+	}
+}
+
+func checkValue(v uint64) {
+	if v >= moved {
+		panic("hashmap: value is reserved")
+	}
+}
+
+// Load returns the value stored for k.
+func (m *Map) Load(k uint64) (v uint64, ok bool) {
+	if k == 0 {
+		return 0, false
+	}
+	for t := m.cur.Load(); t != nil; t = t.next.Load() {
+		v, found, next := t.load(k)
+		if !next {
+			return v, found
+		}
+	}
+	return 0, false
+}
+
+// load probes t for k. next reports that the answer lives in a newer table.
+func (t *table) load(k uint64) (v uint64, found, next bool) {
+	i := hash(k) & t.mask
+	for n := uint64(0); n <= t.mask; n++ {
+		b := t.buckets[i].Load()
+		switch {
+		case b.Lo == 0 && b.Hi == moved:
+			return 0, false, true
+		case b.Lo == 0:
+			return 0, false, false
+		case b.Lo == k && b.Hi == moved:
+			return 0, false, true
+		case b.Lo == k:
+			return b.Hi, b.Hi != Tombstone, false
+		}
+		i = (i + 1) & t.mask
+	}
+	return 0, false, true
+}
+
+// Store sets the value for k.
+func (m *Map) Store(k, v uint64) {
+	checkKey(k)
+	checkValue(v)
+	m.put(k, v, false)
+}
+
+// LoadOrStore returns the existing value for k if present. Otherwise it stores
+// v and returns it. loaded reports whether the value was loaded.
+func (m *Map) LoadOrStore(k, v uint64) (actual uint64, loaded bool) {
+	checkKey(k)
+	checkValue(v)
+	return m.put(k, v, true)
+}
+
+// Delete removes k.
+func (m *Map) Delete(k uint64) {
+	if k == 0 {
+		return
+	}
+	m.put(k, Tombstone, false)
+}
+
+// put writes v for k, or, with ifAbsent, only when k has no live value. Writing
+// Tombstone deletes.
+func (m *Map) put(k, v uint64, ifAbsent bool) (actual uint64, loaded bool) {
+	for {
+		t := m.cur.Load()
+		for t != nil {
+			actual, loaded, done := m.putIn(t, k, v, ifAbsent)
+			if done {
+				return actual, loaded
+			}
+			t = t.next.Load()
+		}
+	}
+}
+
+// putIn attempts the write in t. done is false if the operation must continue
+// in t's successor, which then exists.
+func (m *Map) putIn(t *table, k, v uint64, ifAbsent bool) (actual uint64, loaded, done bool) {
+	i := hash(k) & t.mask
+	for n := uint64(0); n <= t.mask; n++ {
+		c := &t.buckets[i]
+		b := c.Load()
+		for {
+			if b.Lo == 0 && b.Hi == moved || b.Lo == k && b.Hi == moved {
+				m.helpMigrate(t)
+				return 0, false, false
+			}
+			if b.Lo == 0 {
+				if v == Tombstone {
+					// Deleting an absent key.
+					return 0, false, true
+				}
+				if !t.addKey() {
+					// Do not add keys to a table that is full or being
+					// replaced. Marking the bucket first keeps readers of t
+					// from missing k once it is stored in the successor.
+					m.grow(t)
+					if prev, ok := c.AcqRel(b, dwcas.Uint128{Hi: moved}); !ok {
+						b = prev
+						continue
+					}
+					return 0, false, false
+				}
+				prev, ok := c.AcqRel(b, dwcas.Uint128{Lo: k, Hi: v})
+				if ok {
+					m.count.Add(1)
+					return v, false, true
+				}
+				t.live.Add(-1)
+				t.used.Add(-1)
+				b = prev
+				continue
+			}
+			if b.Lo != k {
+				break
+			}
+			live := b.Hi != Tombstone
+			if ifAbsent && live {
+				return b.Hi, true, true
+			}
+			if v == Tombstone && !live {
+				return 0, false, true
+			}
+			revive := !live && v != Tombstone
+			if revive && !t.addLive() {
+				// A deleted key is revived in the successor, like a new
+				// one, so that the successor's size stays a bound.
+				m.grow(t)
+				if prev, ok := c.AcqRel(b, dwcas.Uint128{Lo: k, Hi: moved}); !ok {
+					b = prev
+					continue
+				}
+				return 0, false, false
+			}
+			prev, ok := c.AcqRel(b, dwcas.Uint128{Lo: k, Hi: v})
+			if ok {
+				switch {
+				case v == Tombstone:
+					t.live.Add(-1)
+					m.count.Add(-1)
+				case revive:
+					m.count.Add(1)
+				}
+				return v, false, true
+			}
+			if revive {
+				t.live.Add(-1)
+			}
+			b = prev
+		}
+		i = (i + 1) & t.mask
+	}
+	// No empty bucket and no match: t is saturated.
+	m.grow(t)
+	return 0, false, false
+}
+
+// grow makes sure t has a successor and helps migrate into it.
+func (m *Map) grow(t *table) {
+	if t.next.Load() == nil {
+		// Finish older migrations first so that at most two tables are
+		// ever live.
+		for c := m.cur.Load(); c != t && c != nil; c = m.cur.Load() {
+			m.helpMigrate(c)
+		}
+		// Sealing t bounds what migration copies, so the successor
+		// holds it at a load factor of at most a quarter.
+		nt := newTable(t.seal() * 4)
+		t.next.CompareAndSwap(nil, nt)
+	}
+	m.helpMigrate(t)
+}
+
+// helpMigrate migrates chunks of t until none is left unclaimed, then waits
+// for the other migrators and retires t.
+func (m *Map) helpMigrate(t *table) {
+	nt := t.next.Load()
+	if nt == nil {
+		return
+	}
+	total := int64(len(t.buckets))
+	for {
+		start := t.claimed.Add(chunk) - chunk
+		if start >= total {
+			break
+		}
+		end := min(start+chunk, total)
+		for i := start; i < end; i++ {
+			migrateBucket(&t.buckets[i], nt)
+		}
+		t.migrated.Add(end - start)
+	}
+	for t.migrated.Load() < total {
+		// Another goroutine owns the remaining chunks.
+		if m.cur.Load() != t {
+			return
+		}
+		runtime.Gosched()
+	}
+	m.cur.CompareAndSwap(t, nt)
+}
+
+// migrateBucket copies the content of c into nt and marks c as moved. The
+// goroutine that claimed c's chunk is the only one writing c's key to nt until
+// c is marked, so the copy can overwrite. Since c's table is sealed, c can
+// meanwhile only be updated or deleted; a delete after the copy is carried to
+// nt as a tombstone.
+func migrateBucket(c *dwcas.Uint128, nt *table) {
+	b := c.Load()
+	copied := false
+	for {
+		var mark dwcas.Uint128
+		switch {
+		case b.Hi == moved:
+			// Marked by a writer that continued in nt.
+			return
+		case b.Lo == 0:
+			mark = dwcas.Uint128{Hi: moved}
+		case b.Hi == Tombstone:
+			if copied {
+				nt.copyIn(b.Lo, Tombstone)
+			}
+			mark = dwcas.Uint128{Lo: b.Lo, Hi: moved}
+		default:
+			nt.copyIn(b.Lo, b.Hi)
+			copied = true
+			mark = dwcas.Uint128{Lo: b.Lo, Hi: moved}
+		}
+		prev, ok := c.AcqRel(b, mark)
+		if ok {
+			return
+		}
+		b = prev
+	}
+}
+
+// copyIn writes k=v, or deletes k if v is Tombstone, in t during migration.
+// Migration copies no more keys
+// than the sealed count t was sized for, and writers fill at most half of t,
+// so a bucket is always found.
+func (t *table) copyIn(k, v uint64) {
+	i := hash(k) & t.mask
+	for n := uint64(0); n <= t.mask; n++ {
+		c := &t.buckets[i]
+		b := c.Load()
+		for b == (dwcas.Uint128{}) || b.Lo == k {
+			prev, ok := c.AcqRel(b, dwcas.Uint128{Lo: k, Hi: v})
+			if ok {
+				if b.Lo == 0 {
+					t.used.Add(1)
+				}
+				switch wasLive := b.Lo != 0 && b.Hi != Tombstone; {
+				case v == Tombstone && wasLive:
+					t.live.Add(-1)
+				case v != Tombstone && !wasLive:
+					t.live.Add(1)
+				}
+				return
+			}
+			b = prev
+		}
+		i = (i + 1) & t.mask
+	}
+	panic("hashmap: migration target is full")
+}
+
+// Range calls f for each entry until f returns false. Range does not
+// correspond to a consistent snapshot: entries stored or deleted concurrently
+// may or may not be visited, but no entry is visited twice.
+func (m *Map) Range(f func(k, v uint64) bool) {
+	t := m.cur.Load()
+	for t.next.Load() != nil {
+		// Finish a pending resize so that no entry is hidden in the
+		// successor.
+		m.helpMigrate(t)
+		t = m.cur.Load()
+	}
+	for i := range t.buckets {
+		b := t.buckets[i].Load()
+		if b.Lo == 0 {
+			continue
+		}
+		v, ok := b.Hi, b.Hi != Tombstone
+		if b.Hi == moved {
+			v, ok = m.loadFrom(t.next.Load(), b.Lo)
+		}
+		if ok && !f(b.Lo, v) {
+			return
+		}
+	}
+}
+
+func (m *Map) loadFrom(t *table, k uint64) (uint64, bool) {
+	for ; t != nil; t = t.next.Load() {
+		v, found, next := t.load(k)
+		if !next {
+			return v, found
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,258 @@
+package hashmap_test
+
+import (
+	"sync"
+	"testing"
+
+	"code.hybscloud.com/dwcas/hashmap"
+)
+
+func TestMap_Sequential(t *testing.T) {
+	m := hashmap.New(4)
+	ref := map[uint64]uint64{}
+	key := func(i uint64) uint64 { return i * 0x9e3779b97f4a7c15 }
+	for i := uint64(1); i <= 5000; i++ {
+		k := key(i)
+		if k == 0 {
+			continue
+		}
+		m.Store(k, i)
+		ref[k] = i
+		if i%3 == 0 {
+			m.Delete(k)
+			delete(ref, k)
+		}
+		if i%5 == 0 {
+			m.Store(k, i+1)
+			ref[k] = i + 1
+		}
+	}
+	if m.Len() != len(ref) {
+		t.Fatalf("Len: got %d, want %d", m.Len(), len(ref))
+	}
+	for k, want := range ref {
+		if v, ok := m.Load(k); !ok || v != want {
+			t.Fatalf("Load(%#x): got (%d, %v), want (%d, true)", k, v, ok, want)
+		}
+	}
+	if _, ok := m.Load(key(3)); ok {
+		t.Fatalf("deleted key still present")
+	}
+
+	seen := 0
+	m.Range(func(k, v uint64) bool {
+		if ref[k] != v {
+			t.Fatalf("Range(%#x): got %d, want %d", k, v, ref[k])
+		}
+		seen++
+		return true
+	})
+	if seen != len(ref) {
+		t.Fatalf("Range visited %d entries, want %d", seen, len(ref))
+	}
+}
+
+func TestMap_LoadOrStore(t *testing.T) {
+	m := hashmap.New(0)
+	if v, loaded := m.LoadOrStore(1, 10); loaded || v != 10 {
+		t.Fatalf("first LoadOrStore: got (%d, %v)", v, loaded)
+	}
+	if v, loaded := m.LoadOrStore(1, 20); !loaded || v != 10 {
+		t.Fatalf("second LoadOrStore: got (%d, %v)", v, loaded)
+	}
+	m.Delete(1)
+	if v, loaded := m.LoadOrStore(1, 30); loaded || v != 30 {
+		t.Fatalf("LoadOrStore after Delete: got (%d, %v)", v, loaded)
+	}
+	if m.Len() != 1 {
+		t.Fatalf("Len: got %d, want 1", m.Len())
+	}
+}
+
+func TestMap_ReservedPanics(t *testing.T) {
+	m := hashmap.New(0)
+	for name, f := range map[string]func(){
+		"key 0":     func() { m.Store(0, 1) },
+		"tombstone": func() { m.Store(1, hashmap.Tombstone) },
+		"moved":     func() { m.LoadOrStore(1, hashmap.Tombstone-1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestMap_ConcurrentResize(t *testing.T) {
+	const workers = 8
+	perWorker := uint64(20000)
+	if testing.Short() {
+		perWorker = 2000
+	}
+	m := hashmap.New(1)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := uint64(0); w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			base := w*perWorker + 1
+			for i := base; i < base+perWorker; i++ {
+				m.Store(i, i*2)
+				if v, ok := m.Load(i); !ok || v != i*2 {
+					t.Errorf("Load(%d) after Store: got (%d, %v)", i, v, ok)
+					return
+				}
+				if i%4 == 0 {
+					m.Delete(i)
+					if _, ok := m.Load(i); ok {
+						t.Errorf("Load(%d) after Delete succeeded", i)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	want := 0
+	for i := uint64(1); i <= workers*perWorker; i++ {
+		v, ok := m.Load(i)
+		if i%4 == 0 {
+			if ok {
+				t.Fatalf("deleted key %d present", i)
+			}
+			continue
+		}
+		want++
+		if !ok || v != i*2 {
+			t.Fatalf("Load(%d): got (%d, %v), want (%d, true)", i, v, ok, i*2)
+		}
+	}
+	if m.Len() != want {
+		t.Fatalf("Len: got %d, want %d", m.Len(), want)
+	}
+}
+
+func TestMap_ConcurrentSameKeys(t *testing.T) {
+	const workers = 8
+	m := hashmap.New(0)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := uint64(1); i <= 2000; i++ {
+				m.LoadOrStore(i, i)
+				if v, ok := m.Load(i); !ok || v != i {
+					t.Errorf("Load(%d): got (%d, %v)", i, v, ok)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if m.Len() != 2000 {
+		t.Fatalf("Len: got %d, want 2000", m.Len())
+	}
+}
+
+// Keys deleted before a resize and stored again while it runs must fit in the
+// successor, although few keys were live when it was sized.
+func TestMap_ReviveDuringResize(t *testing.T) {
+	const (
+		workers = 4
+		revived = 30000 // just below the resize threshold of New(1 << 15)
+	)
+	rounds := 5
+	if testing.Short() {
+		rounds = 1
+	}
+	for r := 0; r < rounds; r++ {
+		m := hashmap.New(1 << 15)
+		for k := uint64(1); k <= revived; k++ {
+			m.Store(k, k)
+			m.Delete(k)
+		}
+		var wg sync.WaitGroup
+		wg.Add(workers + 1)
+		for w := uint64(0); w < workers; w++ {
+			go func() {
+				defer wg.Done()
+				for k := 1 + w; k <= revived; k += workers {
+					m.Store(k, k)
+				}
+			}()
+		}
+		go func() {
+			defer wg.Done()
+			for k := uint64(revived + 1); k <= 2*revived; k++ {
+				m.Store(k, k)
+			}
+		}()
+		wg.Wait()
+		for k := uint64(1); k <= 2*revived; k++ {
+			if v, ok := m.Load(k); !ok || v != k {
+				t.Fatalf("round %d: Load(%d): got (%d, %v)", r, k, v, ok)
+			}
+		}
+		if m.Len() != 2*revived {
+			t.Fatalf("round %d: Len: got %d, want %d", r, m.Len(), 2*revived)
+		}
+	}
+}
+
+// Keys deleted while their bucket is being migrated must stay deleted in the
+// successor.
+func TestMap_DeleteDuringResize(t *testing.T) {
+	const (
+		deleters  = 4
+		inserters = 3
+		deleted   = 30000 // just below the resize threshold of New(1 << 15)
+		inserted  = 60000
+	)
+	rounds := 5
+	if testing.Short() {
+		rounds = 1
+	}
+	for r := 0; r < rounds; r++ {
+		m := hashmap.New(1 << 15)
+		for k := uint64(1); k <= deleted; k++ {
+			m.Store(k, k)
+		}
+		var wg sync.WaitGroup
+		wg.Add(deleters + inserters)
+		for w := uint64(0); w < deleters; w++ {
+			go func() {
+				defer wg.Done()
+				for k := 1 + w; k <= deleted; k += deleters {
+					m.Delete(k)
+				}
+			}()
+		}
+		for w := uint64(0); w < inserters; w++ {
+			go func() {
+				defer wg.Done()
+				for k := deleted + 1 + w; k <= deleted+inserted; k += inserters {
+					m.Store(k, k)
+				}
+			}()
+		}
+		wg.Wait()
+		for k := uint64(1); k <= deleted+inserted; k++ {
+			v, ok := m.Load(k)
+			if k <= deleted && ok {
+				t.Fatalf("round %d: deleted key %d present with value %d", r, k, v)
+			}
+			if k > deleted && (!ok || v != k) {
+				t.Fatalf("round %d: Load(%d): got (%d, %v)", r, k, v, ok)
+			}
+		}
+		if m.Len() != inserted {
+			t.Fatalf("round %d: Len: got %d, want %d", r, m.Len(), inserted)
+		}
+	}
+}
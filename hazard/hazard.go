@@ -0,0 +1,204 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package hazard provides hazard-pointer based safe memory reclamation for
+// lock-free structures whose nodes live in index-addressed pools.
+//
+// Tags in a 128-bit head word only postpone ABA: a reader that loaded an index
+// may still dereference a node that another goroutine has already recycled.
+// With hazard pointers a reader publishes the index it is about to use in a
+// hazard slot, and a writer that unlinks a node retires it instead of freeing
+// it. A retired index is handed back to the pool only once no hazard slot
+// holds it.
+//
+// Typical use with a head word whose Lo holds index+1 (0 meaning empty):
+//
+//	rec := d.Acquire()
+//	defer rec.Release()
+//	h := rec.Protect(0, head, func(v dwcas.Uint128) (uint64, bool) {
+//		return v.Lo - 1, v.Lo != 0
+//	})
+//	// nodes[h.Lo-1] cannot be recycled until rec.Clear(0).
+package hazard
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"code.hybscloud.com/dwcas"
+)
+
+const cacheLine = 64
+
+// slot is one hazard pointer. It holds the protected index plus one; zero means
+// empty.
+type slot struct {
+	v atomic.Uint64
+	_ [cacheLine - 8]byte
+}
+
+// Domain is the set of hazard slots shared by every reader and reclaimer of one
+// data structure.
+type Domain struct {
+	records   []Record
+	free      func(index uint64)
+	threshold int
+}
+
+// Record is a goroutine's share of a Domain: a fixed number of hazard slots
+// and a private list of retired indices.
+//
+// A Record must be used by one goroutine at a time.
+type Record struct {
+	d       *Domain
+	inUse   atomic.Bool
+	slots   []slot
+	retired []uint64
+}
+
+// NewDomain returns a domain of records records with slots hazard slots each.
+// free is called with every retired index once it is safe to reuse.
+//
+// NewDomain panics if records or slots is not positive or free is nil.
+func NewDomain(records, slots int, free func(index uint64)) *Domain {
+	if records <= 0 || slots <= 0 {
+		panic("hazard: NewDomain: records and slots must be positive")
+	}
+	if free == nil {
+		panic("hazard: NewDomain: nil free function")
+	}
+	d := &Domain{
+		records: make([]Record, records),
+		free:    free,
+		// Scanning once the retired list is twice the number of hazard slots
+		// reclaims at least half of it per scan.
+		threshold: max(2*records*slots, 64),
+	}
+	for i := range d.records {
+		d.records[i].d = d
+		d.records[i].slots = make([]slot, slots)
+	}
+	return d
+}
+
+// Acquire claims an unused record, or returns nil if all records are in use.
+func (d *Domain) Acquire() *Record {
+	for i := range d.records {
+		r := &d.records[i]
+		if !r.inUse.Load() && r.inUse.CompareAndSwap(false, true) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Release clears r's hazard slots, reclaims what it can and returns r to the
+// domain. Indices that are still protected stay retired on the record and are
+// reclaimed by a later owner.
+func (r *Record) Release() {
+	for i := range r.slots {
+		r.slots[i].v.Store(0)
+	}
+	r.Scan()
+	r.inUse.Store(false)
+}
+
+// Protect loads head, publishes the index extracted from it in slot i and
+// returns the head value once the publication is known to have happened before
+// any reclaimer could free that index.
+//
+// index reports the node index referenced by a head value, or false if the
+// value references no node; in that case slot i is cleared.
+func (r *Record) Protect(i int, head *dwcas.Uint128, index func(dwcas.Uint128) (uint64, bool)) dwcas.Uint128 {
+	s := &r.slots[i]
+	v := head.Load()
+	for {
+		idx, ok := index(v)
+		if !ok {
+			s.v.Store(0)
+			return v
+		}
+		s.v.Store(idx + 1)
+		// The hazard must be visible before head is validated again;
+		// this pairs with the full barrier in Scan.
+		dwcas.BarrierFull()
+		w := head.Load()
+		if w == v {
+			return v
+		}
+		v = w
+	}
+}
+
+// Set publishes index in slot i without validation. The caller must check that
+// the node is still reachable after Set returns, for example by re-reading the
+// link it came from.
+func (r *Record) Set(i int, index uint64) {
+	r.slots[i].v.Store(index + 1)
+	dwcas.BarrierFull()
+}
+
+// Clear empties slot i.
+func (r *Record) Clear(i int) {
+	r.slots[i].v.Store(0)
+}
+
+// Retire hands an unlinked index over for reclamation. It runs [Record.Scan]
+// once enough indices have accumulated.
+func (r *Record) Retire(index uint64) {
+	r.retired = append(r.retired, index)
+	if len(r.retired) >= r.d.threshold {
+		r.Scan()
+	}
+}
+
+// Scan frees every retired index that no hazard slot of the domain protects
+// and returns how many were freed.
+func (r *Record) Scan() int {
+	if len(r.retired) == 0 {
+		return 0
+	}
+	// Unlinks that preceded Retire must be visible before the slots are read;
+	// this pairs with the full barrier in Protect.
+	dwcas.BarrierFull()
+	protected := r.d.snapshot()
+	kept := r.retired[:0]
+	freed := 0
+	for _, idx := range r.retired {
+		if _, found := slices.BinarySearch(protected, idx); found {
+			kept = append(kept, idx)
+			continue
+		}
+		r.d.free(idx)
+		freed++
+	}
+	clear(r.retired[len(kept):])
+	r.retired = kept
+	return freed
+}
+
+// Retired returns the number of indices retired on r but not yet freed.
+func (r *Record) Retired() int {
+	return len(r.retired)
+}
+
+// snapshot returns the sorted set of currently protected indices.
+func (d *Domain) snapshot() []uint64 {
+	var out []uint64
+	for i := range d.records {
+		for j := range d.records[i].slots {
+			if v := d.records[i].slots[j].v.Load(); v != 0 {
+				out = append(out, v-1)
+			}
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Protected reports whether any hazard slot of d currently holds index.
+func (d *Domain) Protected(index uint64) bool {
+	_, found := slices.BinarySearch(d.snapshot(), index)
+	return found
+}
@@ -0,0 +1,206 @@
+package hazard_test
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/hazard"
+)
+
+func headIndex(v dwcas.Uint128) (uint64, bool) {
+	return v.Lo - 1, v.Lo != 0
+}
+
+func TestScan_KeepsProtectedIndex(t *testing.T) {
+	var freed []uint64
+	d := hazard.NewDomain(2, 1, func(i uint64) { freed = append(freed, i) })
+	reader := d.Acquire()
+	writer := d.Acquire()
+	if reader == nil || writer == nil {
+		t.Fatalf("Acquire returned nil with free records")
+	}
+	if d.Acquire() != nil {
+		t.Fatalf("Acquire must return nil when all records are in use")
+	}
+
+	head := dwcas.New(6, 1) // index 5
+	if got := reader.Protect(0, head, headIndex); got != (dwcas.Uint128{Lo: 6, Hi: 1}) {
+		t.Fatalf("Protect returned %v", got)
+	}
+	if !d.Protected(5) {
+		t.Fatalf("index 5 should be protected")
+	}
+
+	writer.Retire(5)
+	writer.Retire(9)
+	if n := writer.Scan(); n != 1 || len(freed) != 1 || freed[0] != 9 {
+		t.Fatalf("Scan freed %d (%v), want only index 9", n, freed)
+	}
+	if writer.Retired() != 1 {
+		t.Fatalf("protected index must stay retired")
+	}
+
+	reader.Clear(0)
+	if n := writer.Scan(); n != 1 || freed[1] != 5 {
+		t.Fatalf("Scan after Clear freed %d (%v), want index 5", n, freed)
+	}
+	reader.Release()
+	writer.Release()
+	if d.Acquire() == nil {
+		t.Fatalf("released records must be reusable")
+	}
+}
+
+func TestProtect_EmptyHeadClearsSlot(t *testing.T) {
+	d := hazard.NewDomain(1, 1, func(uint64) {})
+	r := d.Acquire()
+	r.Set(0, 3)
+	if got := r.Protect(0, dwcas.New(0, 7), headIndex); got.Lo != 0 {
+		t.Fatalf("Protect of empty head returned %v", got)
+	}
+	if d.Protected(3) {
+		t.Fatalf("Protect of an empty head must clear the slot")
+	}
+}
+
+func TestRelease_HandsOverProtectedRetirees(t *testing.T) {
+	var freed atomic.Int64
+	d := hazard.NewDomain(2, 1, func(uint64) { freed.Add(1) })
+	a := d.Acquire()
+	b := d.Acquire()
+	a.Set(0, 1)
+	b.Retire(1)
+	b.Release()
+	if freed.Load() != 0 {
+		t.Fatalf("Release freed a protected index")
+	}
+	a.Release()
+	for r := d.Acquire(); r != nil; r = d.Acquire() {
+		r.Scan()
+	}
+	if freed.Load() != 1 {
+		t.Fatalf("a later owner must reclaim the handed-over index")
+	}
+}
+
+// node is a pool entry of the Treiber stack below. gen changes whenever the
+// node is freed, so a reader can tell whether its node was recycled while it
+// was protected.
+type node struct {
+	next atomic.Uint64
+	gen  atomic.Uint64
+}
+
+func TestTreiberStack_ProtectedNodesAreNeverRecycled(t *testing.T) {
+	const (
+		nodes   = 64
+		workers = 8
+	)
+	iters := 20000
+	if testing.Short() {
+		iters = 2000
+	}
+
+	pool := make([]node, nodes)
+	var mu sync.Mutex
+	var freeList []uint64
+	d := hazard.NewDomain(workers, 1, func(i uint64) {
+		pool[i].gen.Add(1)
+		mu.Lock()
+		freeList = append(freeList, i)
+		mu.Unlock()
+	})
+	alloc := func() (uint64, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(freeList) == 0 {
+			return 0, false
+		}
+		i := freeList[len(freeList)-1]
+		freeList = freeList[:len(freeList)-1]
+		return i, true
+	}
+	for i := uint64(0); i < nodes; i++ {
+		freeList = append(freeList, i)
+	}
+
+	head := dwcas.New(0, 0)
+	push := func(i uint64) {
+		old := head.Load()
+		for {
+			pool[i].next.Store(old.Lo)
+			prev, ok := head.AcqRel(old, dwcas.Uint128{Lo: i + 1, Hi: old.Hi + 1})
+			if ok {
+				return
+			}
+			old = prev
+		}
+	}
+
+	var recycled atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			r := d.Acquire()
+			defer r.Release()
+			for it := 0; it < iters; it++ {
+				if it%2 == 0 {
+					if i, ok := alloc(); ok {
+						push(i)
+					}
+					continue
+				}
+				for {
+					h := r.Protect(0, head, headIndex)
+					if h.Lo == 0 {
+						break
+					}
+					i := h.Lo - 1
+					gen := pool[i].gen.Load()
+					next := pool[i].next.Load()
+					runtime.Gosched()
+					_, ok := head.AcqRel(h, dwcas.Uint128{Lo: next, Hi: h.Hi + 1})
+					if pool[i].gen.Load() != gen {
+						recycled.Add(1)
+					}
+					r.Clear(0)
+					if ok {
+						r.Retire(i)
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := recycled.Load(); n != 0 {
+		t.Fatalf("%d protected nodes were recycled while held", n)
+	}
+
+	// Drain: every node is either on the stack, retired or free.
+	r := d.Acquire()
+	for {
+		h := head.Load()
+		if h.Lo == 0 {
+			break
+		}
+		head.AcqRel(h, dwcas.Uint128{Lo: pool[h.Lo-1].next.Load(), Hi: h.Hi + 1})
+		r.Retire(h.Lo - 1)
+	}
+	r.Release()
+	for {
+		s := d.Acquire()
+		if s == nil {
+			break
+		}
+		s.Scan()
+	}
+	if len(freeList) != nodes {
+		t.Fatalf("nodes leaked: %d of %d back in the pool", len(freeList), nodes)
+	}
+}
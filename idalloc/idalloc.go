@@ -0,0 +1,243 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package idalloc provides a bounded lock-free allocator of integer IDs built
+// on a hierarchy of 128-bit bitmap words.
+//
+// Level 0 holds one bit per ID, set while the ID is allocated. Each word of a
+// higher level summarizes 128 words of the level below: its bit j is set when
+// child word j is full. [Allocator.Alloc] walks down from the single root word
+// along clear summary bits and claims an ID with one CAS on a leaf word, so an
+// allocation touches one word per level, O(log n) words in all.
+//
+// Only the leaf CAS decides who owns an ID, so an ID is never handed out
+// twice. Summary bits are hints kept correct by CAS transitions: a goroutine
+// that sets a summary bit re-checks the child afterwards and clears the bit
+// again if an ID was freed meanwhile, and a goroutine that frees an ID in a
+// full word clears the summary bit above it. A set summary bit therefore never
+// hides a free ID for longer than a concurrent operation is in progress.
+// A clear summary bit over a full child is harmless; Alloc repairs it when it
+// descends there.
+package idalloc
+
+import (
+	"math/bits"
+	"runtime"
+	"sync/atomic"
+
+	"code.hybscloud.com/dwcas"
+)
+
+const fanout = 128
+
+var full = dwcas.Uint128{Lo: ^uint64(0), Hi: ^uint64(0)}
+
+func bit(b int) dwcas.Uint128 {
+	return dwcas.Uint128{Lo: 1}.Lsh(uint(b))
+}
+
+// lowestClear returns the index of the lowest clear bit of w, which must not
+// be full.
+func lowestClear(w dwcas.Uint128) int {
+	if w.Lo != ^uint64(0) {
+		return bits.TrailingZeros64(^w.Lo)
+	}
+	return 64 + bits.TrailingZeros64(^w.Hi)
+}
+
+// Allocator hands out IDs in [0, Cap()).
+type Allocator struct {
+	levels [][]dwcas.Uint128 // levels[0] are the leaves; the last level is one word
+	n      int
+	inUse  atomic.Int64
+}
+
+// New returns an allocator of n IDs, all free.
+//
+// New panics if n is not positive.
+func New(n int) *Allocator {
+	if n <= 0 {
+		panic("idalloc: New: capacity must be positive")
+	}
+	a := &Allocator{n: n}
+	entries := n
+	for {
+		words := (entries + fanout - 1) / fanout
+		level := dwcas.NewSlice(words)
+		// Entries past the end are permanently full, so that they are never
+		// allocated and do not keep their word from becoming full.
+		if rem := entries % fanout; rem != 0 {
+			level[words-1] = full.Lsh(uint(rem))
+		}
+		a.levels = append(a.levels, level)
+		if words == 1 {
+			return a
+		}
+		entries = words
+	}
+}
+
+// Cap returns the number of IDs.
+func (a *Allocator) Cap() int {
+	return a.n
+}
+
+// InUse returns the number of allocated IDs. Concurrent operations may or may
+// not be reflected.
+func (a *Allocator) InUse() int {
+	return int(a.inUse.Load())
+}
+
+// Alloc allocates the lowest free ID it finds. ok is false if every ID is
+// allocated.
+//
+// Under concurrency the result is the lowest free ID along the path the
+// summary bits indicated, which need not be the lowest free ID overall.
+func (a *Allocator) Alloc() (id int, ok bool) {
+	top := len(a.levels) - 1
+	for spins := 0; ; spins++ {
+		root := a.levels[top][0].Load()
+		if root == full {
+			if int(a.inUse.Load()) >= a.n {
+				return 0, false
+			}
+			// A summary bit is set over an ID being freed or claimed
+			// right now; wait for that operation to settle.
+			if spins > 16 {
+				runtime.Gosched()
+			}
+			continue
+		}
+		if id, ok := a.descend(top, 0, root); ok {
+			return id, true
+		}
+	}
+}
+
+// descend searches below word idx of level l, whose value is w, and claims an
+// ID. It returns false if a summary bit on the way was stale.
+func (a *Allocator) descend(l, idx int, w dwcas.Uint128) (int, bool) {
+	for ; l > 0; l-- {
+		idx = idx*fanout + lowestClear(w)
+		w = a.levels[l-1][idx].Load()
+		if w == full {
+			// The summary above claims this word has room: repair it.
+			a.markFull(l-1, idx)
+			return 0, false
+		}
+	}
+	p := &a.levels[0][idx]
+	for w != full {
+		b := lowestClear(w)
+		next := w.Or(bit(b))
+		prev, ok := p.AcqRel(w, next)
+		if ok {
+			a.inUse.Add(1)
+			if next == full {
+				a.markFull(0, idx)
+			}
+			return idx*fanout + b, true
+		}
+		w = prev
+	}
+	a.markFull(0, idx)
+	return 0, false
+}
+
+// Reserve allocates the given ID if it is free and reports whether it did.
+func (a *Allocator) Reserve(id int) bool {
+	p, m := a.leaf(id)
+	w := p.Load()
+	for {
+		if w.And(m) != (dwcas.Uint128{}) {
+			return false
+		}
+		next := w.Or(m)
+		prev, ok := p.AcqRel(w, next)
+		if ok {
+			a.inUse.Add(1)
+			if next == full {
+				a.markFull(0, id/fanout)
+			}
+			return true
+		}
+		w = prev
+	}
+}
+
+// Free releases an allocated ID.
+//
+// Free panics if id is out of range or not allocated.
+func (a *Allocator) Free(id int) {
+	p, m := a.leaf(id)
+	w := p.Load()
+	for {
+		if w.And(m) == (dwcas.Uint128{}) {
+			panic("idalloc: Free: ID is not allocated")
+		}
+		prev, ok := p.AcqRel(w, w.And(dwcas.Uint128{Lo: ^m.Lo, Hi: ^m.Hi}))
+		if ok {
+			break
+		}
+		w = prev
+	}
+	a.inUse.Add(-1)
+	if w == full {
+		a.clearFull(0, id/fanout)
+	}
+}
+
+// leaf returns the leaf word holding id and the bit for id.
+func (a *Allocator) leaf(id int) (*dwcas.Uint128, dwcas.Uint128) {
+	if uint(id) >= uint(a.n) {
+		panic("idalloc: ID out of range")
+	}
+	return &a.levels[0][id/fanout], bit(id % fanout)
+}
+
+// markFull records in level l+1 that word idx of level l is full, then
+// re-checks the word and withdraws the record if it is no longer full.
+func (a *Allocator) markFull(l, idx int) {
+	if l+1 == len(a.levels) {
+		return
+	}
+	p, m := &a.levels[l+1][idx/fanout], bit(idx%fanout)
+	old := p.Load()
+	for old.And(m) == (dwcas.Uint128{}) {
+		prev, ok := p.AcqRel(old, old.Or(m))
+		if ok {
+			break
+		}
+		old = prev
+	}
+	if a.levels[l][idx].Load() != full {
+		// An ID below was freed while the bit was being set; its Free
+		// may have looked before the bit was there.
+		a.clearFull(l, idx)
+		return
+	}
+	if old.And(m) == (dwcas.Uint128{}) && old.Or(m) == full {
+		a.markFull(l+1, idx/fanout)
+	}
+}
+
+// clearFull records in level l+1 that word idx of level l has room, and
+// propagates upwards if that word of level l+1 was full.
+func (a *Allocator) clearFull(l, idx int) {
+	if l+1 == len(a.levels) {
+		return
+	}
+	p, m := &a.levels[l+1][idx/fanout], bit(idx%fanout)
+	old := p.Load()
+	for old.And(m) != (dwcas.Uint128{}) {
+		prev, ok := p.AcqRel(old, old.And(dwcas.Uint128{Lo: ^m.Lo, Hi: ^m.Hi}))
+		if ok {
+			if old == full {
+				a.clearFull(l+1, idx/fanout)
+			}
+			return
+		}
+		old = prev
+	}
+}
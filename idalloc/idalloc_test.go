@@ -0,0 +1,207 @@
+package idalloc_test
+
+import (
+	"sync"
+	"testing"
+
+	"code.hybscloud.com/dwcas/idalloc"
+	"code.hybscloud.com/dwcas/model"
+)
+
+func TestAlloc_LowestFirst(t *testing.T) {
+	for _, n := range []int{127, 128, 129, 128 * 128, 128*128 + 5} {
+		a := idalloc.New(n)
+		for want := 0; want < n; want++ {
+			id, ok := a.Alloc()
+			if !ok || id != want {
+				t.Fatalf("n=%d: Alloc = (%d, %v), want (%d, true)", n, id, ok, want)
+			}
+		}
+		if id, ok := a.Alloc(); ok {
+			t.Fatalf("n=%d: Alloc on a full allocator returned %d", n, id)
+		}
+		if a.InUse() != n {
+			t.Fatalf("n=%d: InUse = %d", n, a.InUse())
+		}
+		for _, id := range []int{n - 1, n / 2, 0} {
+			a.Free(id)
+		}
+		for _, want := range []int{0, n / 2, n - 1} {
+			if id, ok := a.Alloc(); !ok || id != want {
+				t.Fatalf("n=%d: Alloc after Free = (%d, %v), want %d", n, id, ok, want)
+			}
+		}
+	}
+}
+
+func TestReserve(t *testing.T) {
+	a := idalloc.New(300)
+	if !a.Reserve(0) || !a.Reserve(200) {
+		t.Fatalf("Reserve of a free ID failed")
+	}
+	if a.Reserve(200) {
+		t.Fatalf("Reserve of an allocated ID succeeded")
+	}
+	if id, _ := a.Alloc(); id != 1 {
+		t.Fatalf("Alloc = %d, want 1", id)
+	}
+	for i := 128; i < 256; i++ {
+		a.Reserve(i)
+	}
+	for want := 2; want < 128; want++ {
+		a.Alloc()
+	}
+	if id, _ := a.Alloc(); id != 256 {
+		t.Fatalf("Alloc skipped past reserved word to %d, want 256", id)
+	}
+	a.Free(200)
+	if id, _ := a.Alloc(); id != 200 {
+		t.Fatalf("Alloc = %d, want freed ID 200", id)
+	}
+}
+
+func TestPanics(t *testing.T) {
+	a := idalloc.New(10)
+	for name, f := range map[string]func(){
+		"New(0)":       func() { idalloc.New(0) },
+		"Free(free)":   func() { a.Free(3) },
+		"Free(-1)":     func() { a.Free(-1) },
+		"Reserve(10)":  func() { a.Reserve(10) },
+		"Free(ranged)": func() { a.Free(10) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestConcurrent_NoDoubleAllocationNoLoss(t *testing.T) {
+	const (
+		n       = 128*128 + 300
+		workers = 8
+	)
+	iters := 20000
+	if testing.Short() {
+		iters = 2000
+	}
+	a := idalloc.New(n)
+	owner := make([]int32, n)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(chan string, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var held []int
+			for i := 0; i < iters; i++ {
+				if len(held) < n/workers && (i%3 != 2 || len(held) == 0) {
+					id, ok := a.Alloc()
+					if !ok {
+						errs <- "Alloc failed with free IDs left"
+						return
+					}
+					mu.Lock()
+					if owner[id] != 0 {
+						mu.Unlock()
+						errs <- "ID allocated twice"
+						return
+					}
+					owner[id] = int32(w + 1)
+					mu.Unlock()
+					held = append(held, id)
+					continue
+				}
+				k := (i * 7) % len(held)
+				id := held[k]
+				held[k] = held[len(held)-1]
+				held = held[:len(held)-1]
+				mu.Lock()
+				owner[id] = 0
+				mu.Unlock()
+				a.Free(id)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Fatal(e)
+	}
+
+	// Every ID not held must still be reachable.
+	inUse := 0
+	for _, o := range owner {
+		if o != 0 {
+			inUse++
+		}
+	}
+	if a.InUse() != inUse {
+		t.Fatalf("InUse = %d, want %d", a.InUse(), inUse)
+	}
+	for i := inUse; i < n; i++ {
+		id, ok := a.Alloc()
+		if !ok {
+			t.Fatalf("lost %d IDs", n-i)
+		}
+		if owner[id] != 0 {
+			t.Fatalf("ID %d allocated twice", id)
+		}
+		owner[id] = -1
+	}
+	if _, ok := a.Alloc(); ok {
+		t.Fatalf("Alloc succeeded on a full allocator")
+	}
+}
+
+func TestModel_FillRacesFree(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	rep, err := model.Explore(model.Config{Executions: 1000}, func(mt *model.T) {
+		// Leaf 0 has one free ID, 127. Allocating it marks the leaf full
+		// in the root while ID 5 is freed from it.
+		a := idalloc.New(129)
+		for i := 0; i < 127; i++ {
+			a.Reserve(i)
+		}
+		var got int
+		g := mt.Go(func() { got, _ = a.Alloc() })
+		f := mt.Go(func() { a.Free(5) })
+		g.Join()
+		f.Join()
+		mt.Assert(got == 127 || got == 5 || got == 128, "Alloc = %d", got)
+		free := map[int]bool{}
+		for {
+			id, ok := a.Alloc()
+			if !ok {
+				break
+			}
+			mt.Assert(!free[id] && id != got, "ID %d allocated twice", id)
+			free[id] = true
+		}
+		mt.Assert(len(free) == 2, "%d IDs reachable after the race, want 2", len(free))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A lost ID would leave Alloc spinning on a full root.
+	if rep.Truncated != 0 {
+		t.Fatalf("%d executions did not terminate", rep.Truncated)
+	}
+}
+
+func BenchmarkAllocFree(b *testing.B) {
+	a := idalloc.New(1 << 20)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			id, _ := a.Alloc()
+			a.Free(id)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dwcas
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+)
+
+// Int128 is a signed 128-bit integer in two's complement, Hi<<64 | Lo with Hi
+// carrying the sign, usable as an atomic cell.
+//
+// Its layout is that of [Uint128]:
+//
+//	word 0: Lo
+//	word 1: Hi
+//
+// and the same alignment contract applies: the address of an *Int128 used with
+// the atomic methods MUST be 16-byte aligned. Use [NewInt128], or obtain an
+// aligned *Uint128 and convert it with [AsInt128].
+//
+// The atomic methods go through the same CAS implementation as Uint128. Value
+// arithmetic uses the names of Uint128's (Add, Sub, Mul); the atomic add is
+// AddAndFetch.
+type Int128 struct {
+	Lo uint64
+	Hi int64
+}
+
+// Int128FromInt64 returns v as an Int128.
+func Int128FromInt64(v int64) Int128 {
+	return Int128{Lo: uint64(v), Hi: v >> 63}
+}
+
+// NewInt128 returns a heap-allocated *Int128 holding x whose address is
+// guaranteed to be 16-byte aligned.
+func NewInt128(x Int128) *Int128 {
+	return AsInt128(New(x.Lo, uint64(x.Hi)))
+}
+
+// AsInt128 returns p viewed as an *Int128. The two types share their layout, so
+// atomic operations through either pointer act on the same cell.
+func AsInt128(p *Uint128) *Int128 {
+	return (*Int128)(unsafe.Pointer(p))
+}
+
+func (p *Int128) cell() *Uint128 {
+	return (*Uint128)(unsafe.Pointer(p))
+}
+
+// Load atomically loads *p with acquire ordering. Like [Uint128.Load] it
+// requires writable memory.
+func (p *Int128) Load() Int128 {
+	return p.cell().Load().Int128()
+}
+
+// Store atomically stores x into *p with acq_rel ordering.
+func (p *Int128) Store(x Int128) {
+	p.Swap(x)
+}
+
+// Swap atomically stores x into *p and returns the previous value, with acq_rel
+// ordering.
+func (p *Int128) Swap(x Int128) (old Int128) {
+	return p.cell().swap(x.Uint128()).Int128()
+}
+
+// CompareAndSwap atomically replaces old with new and reports whether it did,
+// with acq_rel ordering on success.
+func (p *Int128) CompareAndSwap(old, new Int128) (swapped bool) {
+	_, swapped = p.cell().AcqRel(old.Uint128(), new.Uint128())
+	return swapped
+}
+
+// AddAndFetch atomically adds delta to *p, wrapping on overflow, and returns
+// the new value, with acq_rel ordering.
+func (p *Int128) AddAndFetch(delta Int128) (new Int128) {
+	d := delta.Uint128()
+	return p.cell().update(func(cur Uint128) Uint128 {
+		return cur.Add(d)
+	}).Int128()
+}
+
+// Uint128 returns the two's complement bits of x as a Uint128.
+func (x Int128) Uint128() Uint128 {
+	return Uint128{Lo: x.Lo, Hi: uint64(x.Hi)}
+}
+
+// Int128 returns the bits of x reinterpreted as a two's complement Int128.
+func (x Uint128) Int128() Int128 {
+	return Int128{Lo: x.Lo, Hi: int64(x.Hi)}
+}
+
+// Add returns x+y, wrapping on overflow.
+func (x Int128) Add(y Int128) Int128 {
+	return x.Uint128().Add(y.Uint128()).Int128()
+}
+
+// Sub returns x-y, wrapping on overflow.
+func (x Int128) Sub(y Int128) Int128 {
+	return x.Uint128().Sub(y.Uint128()).Int128()
+}
+
+// Mul returns x*y, wrapping on overflow.
+func (x Int128) Mul(y Int128) Int128 {
+	return x.Uint128().Mul(y.Uint128()).Int128()
+}
+
+// Neg returns -x; the minimum value is its own negation.
+func (x Int128) Neg() Int128 {
+	return Uint128{}.Sub(x.Uint128()).Int128()
+}
+
+// Abs returns the magnitude of x as a Uint128, which is exact for every value.
+func (x Int128) Abs() Uint128 {
+	if x.Hi < 0 {
+		return x.Neg().Uint128()
+	}
+	return x.Uint128()
+}
+
+// Sign returns -1, 0 or +1 when x < 0, x == 0 or x > 0.
+func (x Int128) Sign() int {
+	switch {
+	case x.Hi < 0:
+		return -1
+	case x.Hi == 0 && x.Lo == 0:
+		return 0
+	}
+	return 1
+}
+
+// Cmp compares x and y and returns -1, 0 or +1 when x < y, x == y or x > y.
+func (x Int128) Cmp(y Int128) int {
+	switch {
+	case x.Hi < y.Hi:
+		return -1
+	case x.Hi > y.Hi:
+		return 1
+	case x.Lo < y.Lo:
+		return -1
+	case x.Lo > y.Lo:
+		return 1
+	}
+	return 0
+}
+
+// QuoRem returns the quotient x/y truncated toward zero and the remainder
+// x%y, which has the sign of x, as for Go's integer operators. The minimum
+// value divided by -1 wraps to the minimum value. QuoRem panics if y is zero.
+func (x Int128) QuoRem(y Int128) (q, r Int128) {
+	uq, ur := x.Abs().QuoRem(y.Abs())
+	q, r = uq.Int128(), ur.Int128()
+	if (x.Hi < 0) != (y.Hi < 0) {
+		q = q.Neg()
+	}
+	if x.Hi < 0 {
+		r = r.Neg()
+	}
+	return q, r
+}
+
+// String returns x in decimal.
+func (x Int128) String() string {
+	if x.Hi < 0 {
+		return "-" + x.Abs().String()
+	}
+	return x.Uint128().String()
+}
+
+// Format implements [fmt.Formatter] with the verbs and flags of
+// [Uint128.Format]; negative values are written with a leading minus sign,
+// and %#v is decimal, as for the built-in signed integer types.
+func (x Int128) Format(f fmt.State, verb rune) {
+	formatInteger(f, verb, "dwcas.Int128", true, x.Hi < 0, x.Abs())
+}
+
+// ParseInt128 parses s as an optionally signed number in the forms accepted by
+// [ParseUint128], such as "-42" or "+0x2a". Errors are of type
+// [*strconv.NumError].
+func ParseInt128(s string) (Int128, error) {
+	const fn = "ParseInt128"
+	neg := false
+	t := s
+	if len(t) > 0 && (t[0] == '-' || t[0] == '+') {
+		neg, t = t[0] == '-', t[1:]
+	}
+	mag, err := ParseUint128(t)
+	if err != nil {
+		return Int128{}, &strconv.NumError{Func: fn, Num: s, Err: err.(*strconv.NumError).Err}
+	}
+	limit := Uint128{Hi: 1 << 63} // magnitude of the minimum value
+	if neg && mag.Cmp(limit) > 0 || !neg && mag.Cmp(limit) >= 0 {
+		return Int128{}, &strconv.NumError{Func: fn, Num: s, Err: strconv.ErrRange}
+	}
+	x := mag.Int128()
+	if neg {
+		x = x.Neg()
+	}
+	return x, nil
+}
+
+// MarshalText implements [encoding.TextMarshaler]; the text is decimal.
+func (x Int128) MarshalText() ([]byte, error) {
+	return x.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender].
+func (x Int128) AppendText(b []byte) ([]byte, error) {
+	if x.Hi < 0 {
+		b = append(b, '-')
+	}
+	return x.Abs().appendBase(b, 10, false), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler]. It accepts the forms
+// accepted by [ParseInt128].
+//
+// UnmarshalText writes *x non-atomically; do not use it on a cell other
+// goroutines access concurrently.
+func (x *Int128) UnmarshalText(text []byte) error {
+	v, err := ParseInt128(string(text))
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler]. As for [Uint128.MarshalJSON], the
+// value is encoded as a decimal string.
+func (x Int128) MarshalJSON() ([]byte, error) {
+	b := append(make([]byte, 0, 42), '"')
+	b, _ = x.AppendText(b)
+	return append(b, '"'), nil
+}
+
+// UnmarshalJSON implements [json.Unmarshaler]. It accepts a string in any form
+// accepted by [ParseInt128], or a bare integer, and leaves *x unchanged for a
+// JSON null.
+//
+// UnmarshalJSON writes *x non-atomically.
+func (x *Int128) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	if err := x.UnmarshalText(data); err != nil {
+		return fmt.Errorf("dwcas: UnmarshalJSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package dwcas_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"sync"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+)
+
+func int128ToBig(x dwcas.Int128) *big.Int {
+	b := toBig(x.Uint128())
+	if x.Hi < 0 {
+		b.Sub(b, two128)
+	}
+	return b
+}
+
+// int128Operands reinterprets mathOperands as signed values, which covers
+// both signs and the extremes.
+func int128Operands(n int) []dwcas.Int128 {
+	var xs []dwcas.Int128
+	for _, u := range mathOperands(n) {
+		xs = append(xs, u.Int128())
+	}
+	return xs
+}
+
+func TestInt128_Arithmetic(t *testing.T) {
+	n := 100
+	if testing.Short() {
+		n = 20
+	}
+	xs := int128Operands(n)
+	for _, x := range xs {
+		bx := int128ToBig(x)
+		for _, y := range xs {
+			by := int128ToBig(y)
+			check := func(op string, got dwcas.Int128, want *big.Int) {
+				t.Helper()
+				if w := fromBig(want).Int128(); got != w {
+					t.Fatalf("%v %s %v: got %v, want %v", bx, op, by, got, w)
+				}
+			}
+			check("+", x.Add(y), new(big.Int).Add(bx, by))
+			check("-", x.Sub(y), new(big.Int).Sub(bx, by))
+			check("*", x.Mul(y), new(big.Int).Mul(bx, by))
+			if got, want := x.Cmp(y), bx.Cmp(by); got != want {
+				t.Fatalf("Cmp(%v, %v): got %d, want %d", bx, by, got, want)
+			}
+			if y == (dwcas.Int128{}) {
+				continue
+			}
+			q, r := x.QuoRem(y)
+			wq, wr := new(big.Int).QuoRem(bx, by, new(big.Int))
+			check("/", q, wq)
+			check("%", r, wr)
+		}
+		if x.Sign() != bx.Sign() || x.Abs() != fromBig(new(big.Int).Abs(bx)) {
+			t.Fatalf("Sign/Abs(%v): got %d, %v", bx, x.Sign(), x.Abs())
+		}
+		if got, want := x.Neg(), fromBig(new(big.Int).Neg(bx)).Int128(); got != want {
+			t.Fatalf("Neg(%v): got %v, want %v", bx, got, want)
+		}
+	}
+	for _, v := range []int64{0, 1, -1, math.MinInt64, math.MaxInt64} {
+		if got := dwcas.Int128FromInt64(v); int128ToBig(got).Int64() != v || !int128ToBig(got).IsInt64() {
+			t.Fatalf("Int128FromInt64(%d): got %v", v, got)
+		}
+	}
+}
+
+func TestInt128_FormatParse(t *testing.T) {
+	formats := []string{"%d", "%v", "%x", "%X", "%#x", "%b", "%o", "%O", "%+d", "% d", "%40d", "%-40d|", "%040d", "%.30d"}
+	for _, x := range int128Operands(200) {
+		bx := int128ToBig(x)
+		for _, f := range formats {
+			var oracle any = bx
+			if bx.IsInt64() {
+				oracle = bx.Int64()
+			}
+			if got, want := fmt.Sprintf(f, x), fmt.Sprintf(f, oracle); got != want {
+				t.Fatalf("Sprintf(%q, %v): got %q, want %q", f, bx, got, want)
+			}
+		}
+		if x.String() != bx.String() {
+			t.Fatalf("String: got %q, want %q", x.String(), bx.String())
+		}
+		for _, s := range []string{x.String(), fmt.Sprintf("%#x", x)} {
+			if y, err := dwcas.ParseInt128(s); err != nil || y != x {
+				t.Fatalf("ParseInt128(%q): got (%v, %v), want %v", s, y, err, x)
+			}
+		}
+	}
+	if x, err := dwcas.ParseInt128("+42"); err != nil || x != dwcas.Int128FromInt64(42) {
+		t.Fatalf("ParseInt128(+42): got (%v, %v)", x, err)
+	}
+	for _, s := range []string{"", "-", "--1", "1-",
+		"170141183460469231731687303715884105728",  // 2^127
+		"-170141183460469231731687303715884105729", // -2^127-1
+	} {
+		if _, err := dwcas.ParseInt128(s); err == nil {
+			t.Errorf("ParseInt128(%q) succeeded", s)
+		} else if _, ok := err.(*strconv.NumError); !ok {
+			t.Errorf("ParseInt128(%q): error %T is not *strconv.NumError", s, err)
+		}
+	}
+	if x, err := dwcas.ParseInt128("-170141183460469231731687303715884105728"); err != nil || x != (dwcas.Int128{Hi: math.MinInt64}) {
+		t.Fatalf("ParseInt128(min): got (%v, %v)", x, err)
+	}
+}
+
+func TestInt128_Encoding(t *testing.T) {
+	type config struct {
+		Offset dwcas.Int128 `json:"offset"`
+	}
+	for _, x := range int128Operands(100) {
+		text, _ := x.MarshalText()
+		if string(text) != x.String() {
+			t.Fatalf("MarshalText(%v): got %q", x, text)
+		}
+		var y dwcas.Int128
+		if err := y.UnmarshalText(text); err != nil || y != x {
+			t.Fatalf("text round trip of %v: got (%v, %v)", x, y, err)
+		}
+
+		j, err := json.Marshal(config{Offset: x})
+		if err != nil || string(j) != `{"offset":"`+x.String()+`"}` {
+			t.Fatalf("json.Marshal(%v): got (%s, %v)", x, j, err)
+		}
+		var c config
+		if err := json.Unmarshal(j, &c); err != nil || c.Offset != x {
+			t.Fatalf("json round trip of %v: got (%v, %v)", x, c.Offset, err)
+		}
+		if err := json.Unmarshal([]byte(`{"offset":`+x.String()+`}`), &c); err != nil || c.Offset != x {
+			t.Fatalf("json bare number %v: got (%v, %v)", x, c.Offset, err)
+		}
+		if err := json.Unmarshal([]byte(`{"offset":null}`), &c); err != nil || c.Offset != x {
+			t.Fatalf("json null over %v: got (%v, %v)", x, c.Offset, err)
+		}
+	}
+	var c config
+	if err := json.Unmarshal([]byte(`{"offset":"1x"}`), &c); err == nil {
+		t.Fatalf("json.Unmarshal of a malformed number succeeded")
+	}
+}
+
+func TestInt128_Atomic(t *testing.T) {
+	p := dwcas.NewInt128(dwcas.Int128FromInt64(-5))
+	if got := p.AddAndFetch(dwcas.Int128FromInt64(3)); got != dwcas.Int128FromInt64(-2) {
+		t.Fatalf("AddAndFetch: got %v, want -2", got)
+	}
+	if p.CompareAndSwap(dwcas.Int128FromInt64(5), dwcas.Int128{}) {
+		t.Fatalf("CompareAndSwap succeeded with a stale old value")
+	}
+	if !p.CompareAndSwap(dwcas.Int128FromInt64(-2), dwcas.Int128FromInt64(7)) || p.Load() != dwcas.Int128FromInt64(7) {
+		t.Fatalf("CompareAndSwap failed")
+	}
+	p.Store(dwcas.Int128FromInt64(-1))
+	if old := p.Swap(dwcas.Int128{}); old != dwcas.Int128FromInt64(-1) {
+		t.Fatalf("Swap: got %v, want -1", old)
+	}
+	u := dwcas.New(0, 0)
+	dwcas.AsInt128(u).AddAndFetch(dwcas.Int128FromInt64(-1))
+	if *u != (dwcas.Uint128{Lo: math.MaxUint64, Hi: math.MaxUint64}) {
+		t.Fatalf("AsInt128 view: got %#x", *u)
+	}
+}
+
+func TestInt128_ConcurrentAdd(t *testing.T) {
+	const workers = 8
+	iters := 10000
+	if testing.Short() {
+		iters = 1000
+	}
+	p := dwcas.NewInt128(dwcas.Int128{})
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			// Half the workers subtract more than the others add, so the
+			// total crosses zero and borrows through Hi.
+			d := dwcas.Int128FromInt64(int64(w + 1))
+			if w%2 == 0 {
+				d = dwcas.Int128FromInt64(-3 * int64(w+1))
+			}
+			for i := 0; i < iters; i++ {
+				p.AddAndFetch(d)
+			}
+		}()
+	}
+	wg.Wait()
+	var want int64
+	for w := 0; w < workers; w++ {
+		if w%2 == 0 {
+			want -= 3 * int64(w+1) * int64(iters)
+		} else {
+			want += int64(w+1) * int64(iters)
+		}
+	}
+	if got := p.Load(); got != dwcas.Int128FromInt64(want) {
+		t.Fatalf("concurrent Add: got %v, want %d", got, want)
+	}
+}
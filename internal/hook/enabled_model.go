@@ -0,0 +1,6 @@
+//go:build dwcas_model
+
+package hook
+
+// Enabled reports whether dwcas entry points consult the installed backend.
+const Enabled = true
@@ -0,0 +1,6 @@
+//go:build !dwcas_model
+
+package hook
+
+// Enabled is false in normal builds; the hook is compiled out.
+const Enabled = false
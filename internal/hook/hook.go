@@ -0,0 +1,62 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package hook connects the dwcas entry points to an optional instrumentation
+// backend.
+//
+// The hook is only consulted by builds tagged `dwcas_model`. In every other
+// build [Enabled] is false and the dwcas entry points compile the hook away.
+package hook
+
+import "sync/atomic"
+
+// Order identifies the memory ordering requested by a dwcas CAS method.
+type Order uint8
+
+const (
+	Relaxed Order = iota
+	Acquire
+	Release
+	AcqRel
+)
+
+// Fence identifies a manual dwcas barrier.
+type Fence uint8
+
+const (
+	FenceAcquire Fence = iota
+	FenceRelease
+	FenceFull
+)
+
+// Backend intercepts dwcas operations.
+//
+// Each method reports handled=true if it fully performed the operation; otherwise
+// the caller falls through to the architecture implementation.
+type Backend interface {
+	Cas128(ptr *uint64, oldLo, oldHi, newLo, newHi uint64, order Order) (prevLo, prevHi uint64, swapped, handled bool)
+	Barrier(fence Fence) (handled bool)
+}
+
+type holder struct{ b Backend }
+
+var current atomic.Pointer[holder]
+
+// Install makes b the active backend. Passing nil removes the backend.
+func Install(b Backend) {
+	if b == nil {
+		current.Store(nil)
+		return
+	}
+	current.Store(&holder{b: b})
+}
+
+// Current returns the active backend, or nil if none is installed.
+func Current() Backend {
+	h := current.Load()
+	if h == nil {
+		return nil
+	}
+	return h.b
+}
@@ -0,0 +1,32 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package proc identifies processes by PID and start time, so that a PID
+// reused by a new process is not mistaken for a dead owner. Package shm and
+// its subpackages use it to decide whether the holder of a shared cell has
+// died.
+package proc
+
+import (
+	"os"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// Self returns this process's identity, {Lo: PID, Hi: start time}.
+func Self() dwcas.Uint128 {
+	pid := os.Getpid()
+	start, ok := StartTime(pid)
+	if !ok {
+		panic("proc: Self: cannot read own start time")
+	}
+	return dwcas.Uint128{Lo: uint64(pid), Hi: start}
+}
+
+// Alive reports whether the process identified by id, as returned by [Self],
+// still runs. A zombie counts as dead.
+func Alive(id dwcas.Uint128) bool {
+	start, ok := StartTime(int(id.Lo))
+	return ok && start == id.Hi
+}
@@ -0,0 +1,37 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package proc
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+)
+
+// StartTime returns the start time of a running process, in clock ticks
+// after boot, from /proc/<pid>/stat. ok is false if the process does not
+// exist or is a zombie.
+func StartTime(pid int) (start uint64, ok bool) {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0, false
+	}
+	// The command name is in parentheses and may contain anything; the
+	// fields after it start with the state (field 3). The start time is
+	// field 22.
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, false
+	}
+	fields := bytes.Fields(stat[i+1:])
+	if len(fields) < 20 {
+		return 0, false
+	}
+	if state := fields[0][0]; state == 'Z' || state == 'X' {
+		return 0, false
+	}
+	start, err = strconv.ParseUint(string(fields[19]), 10, 64)
+	return start, err == nil
+}
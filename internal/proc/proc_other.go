@@ -0,0 +1,13 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package proc
+
+// StartTime cannot tell without /proc: it reports every process as running,
+// with start time 0, so that owners are assumed alive.
+func StartTime(pid int) (start uint64, ok bool) {
+	return 0, true
+}
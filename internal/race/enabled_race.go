@@ -0,0 +1,6 @@
+//go:build race
+
+package race
+
+// Enabled reports whether the build is instrumented by the race detector.
+const Enabled = true
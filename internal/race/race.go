@@ -0,0 +1,10 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package race reports whether the race detector is enabled.
+//
+// The detector does not see the CAS instructions of package dwcas as
+// synchronization, and it reports accesses that race by design, such as the
+// plain accesses of the litmus tests.
+package race
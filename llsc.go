@@ -0,0 +1,76 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dwcas
+
+// VersionedCell is a 64-bit value with load-linked / store-conditional access,
+// for porting algorithms written in LL/SC form.
+//
+// The cell is a Uint128 holding the value in Lo and a version in Hi that
+// every successful store increments. [VersionedCell.LoadLinked] returns both
+// as a [Link]; [VersionedCell.StoreConditional] is a 128-bit CAS against that
+// pair. A store conditional therefore fails if any store succeeded since the
+// load linked, even one that wrote the same value back, which is the property
+// LL/SC algorithms rely on to avoid ABA. Unlike hardware SC it never fails
+// spuriously, and any number of links may be outstanding at once.
+//
+// The emulation is used on every architecture. The arm64 dwcas_llsc backend
+// uses LDXP/STXP inside a single CAS, but the exclusive monitor does not
+// survive a return to Go code, so it cannot back a link held across calls.
+//
+// The version wraps after 2^64 successful stores; a link held across exactly
+// that many stores would be accepted.
+//
+// The zero value holds 0 and is ready to use. A VersionedCell must not be
+// copied after first use. Loads have acquire ordering and successful stores
+// acq_rel ordering.
+type VersionedCell struct {
+	c inlineCell
+}
+
+// Link is the result of [VersionedCell.LoadLinked]: a value and the version it
+// was read at.
+type Link struct {
+	v Uint128
+}
+
+// Value returns the value that was loaded.
+func (l Link) Value() uint64 {
+	return l.v.Lo
+}
+
+// Version returns the version the value was loaded at.
+func (l Link) Version() uint64 {
+	return l.v.Hi
+}
+
+// LoadLinked loads the value and establishes a link for StoreConditional.
+func (c *VersionedCell) LoadLinked() Link {
+	return Link{v: c.c.ptr().Load()}
+}
+
+// StoreConditional stores v if no store has succeeded since l was loaded, and
+// reports whether it did.
+func (c *VersionedCell) StoreConditional(l Link, v uint64) bool {
+	_, ok := c.c.ptr().AcqRel(l.v, Uint128{Lo: v, Hi: l.v.Hi + 1})
+	return ok
+}
+
+// Validate reports whether no store has succeeded since l was loaded (the VL
+// operation of some LL/SC formulations).
+func (c *VersionedCell) Validate(l Link) bool {
+	return c.c.ptr().Load().Hi == l.v.Hi
+}
+
+// Load returns the current value.
+func (c *VersionedCell) Load() uint64 {
+	return c.c.ptr().Load().Lo
+}
+
+// Store unconditionally stores v, invalidating every outstanding link.
+func (c *VersionedCell) Store(v uint64) {
+	c.c.update(func(cur Uint128) Uint128 {
+		return Uint128{Lo: v, Hi: cur.Hi + 1}
+	})
+}
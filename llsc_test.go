@@ -0,0 +1,81 @@
+package dwcas_test
+
+import (
+	"sync"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+)
+
+func TestVersionedCell_LLSC(t *testing.T) {
+	var c dwcas.VersionedCell
+	l := c.LoadLinked()
+	if l.Value() != 0 || !c.Validate(l) {
+		t.Fatalf("zero cell: got %d, valid=%v", l.Value(), c.Validate(l))
+	}
+	if !c.StoreConditional(l, 5) || c.Load() != 5 {
+		t.Fatalf("StoreConditional on a fresh link failed")
+	}
+	if c.StoreConditional(l, 6) || c.Validate(l) {
+		t.Fatalf("a used link still succeeds")
+	}
+	l2 := c.LoadLinked()
+	if l2.Version() != l.Version()+1 {
+		t.Fatalf("version: got %d, want %d", l2.Version(), l.Version()+1)
+	}
+	c.Store(5)
+	if c.StoreConditional(l2, 7) {
+		t.Fatalf("StoreConditional succeeded after an intervening Store")
+	}
+}
+
+func TestVersionedCell_DetectsABA(t *testing.T) {
+	var c dwcas.VersionedCell
+	c.Store(1)
+	l := c.LoadLinked()
+
+	// A -> B -> A: the value matches again but the link must be broken.
+	for _, v := range []uint64{2, 1} {
+		m := c.LoadLinked()
+		if !c.StoreConditional(m, v) {
+			t.Fatalf("StoreConditional(%d) failed", v)
+		}
+	}
+	if c.Load() != l.Value() {
+		t.Fatalf("setup: value %d, want %d", c.Load(), l.Value())
+	}
+	if c.StoreConditional(l, 3) {
+		t.Fatalf("StoreConditional succeeded across A-B-A")
+	}
+}
+
+func TestVersionedCell_ConcurrentCounter(t *testing.T) {
+	const workers = 8
+	iters := 10000
+	if testing.Short() {
+		iters = 1000
+	}
+	var c dwcas.VersionedCell
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for range iters {
+				for {
+					l := c.LoadLinked()
+					if c.StoreConditional(l, l.Value()+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := c.Load(); got != workers*uint64(iters) {
+		t.Fatalf("counter %d, want %d", got, workers*iters)
+	}
+	if v := c.LoadLinked().Version(); v != workers*uint64(iters) {
+		t.Fatalf("version %d, want one per store", v)
+	}
+}
@@ -0,0 +1,41 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mcas
+
+import "code.hybscloud.com/dwcas"
+
+// DCSS is a double-compare single-swap: it atomically replaces expA with newA
+// at a, provided that b holds expB at the same instant. It returns the value
+// found at a and whether the swap happened; prev equals expA when the swap
+// failed only because b did not hold expB.
+//
+// DCSS is the RDCSS that [CAS] is built on, exposed with a caller-chosen
+// control word. It is lock-free: a goroutine that finds a DCSS in progress
+// finishes it rather than waiting.
+//
+// Cell a follows the rules of the package: read it with [DCSSLoad] or [Read],
+// write it only with DCSS or CAS, and keep Hi of its values at most [MaxHi].
+// b is only read. It may be any 16-byte aligned, writable cell that is not
+// itself written through this package, such as a word updated with the
+// [dwcas.Uint128] CAS methods. Its values are unrestricted.
+//
+// DCSS panics if expA or newA has Hi above MaxHi.
+func DCSS(a *dwcas.Uint128, expA, newA dwcas.Uint128, b *dwcas.Uint128, expB dwcas.Uint128) (prev dwcas.Uint128, swapped bool) {
+	checkValue("DCSS", expA)
+	checkValue("DCSS", newA)
+	for {
+		prev, swapped = rdcss(b, expB, a, expA, newA)
+		if !isMCAS(prev) {
+			return prev, swapped
+		}
+		help(prev)
+	}
+}
+
+// DCSSLoad returns the value of a cell written with [DCSS], completing any DCSS
+// in progress on it first. It is the same as [Read].
+func DCSSLoad(a *dwcas.Uint128) dwcas.Uint128 {
+	return Read(a)
+}
@@ -0,0 +1,144 @@
+package mcas_test
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/mcas"
+	"code.hybscloud.com/dwcas/model"
+)
+
+func TestDCSS_Sequential(t *testing.T) {
+	a, b := dwcas.New(5, 0), dwcas.New(1, ^uint64(0))
+	ctrl := dwcas.Uint128{Lo: 1, Hi: ^uint64(0)}
+
+	if prev, ok := mcas.DCSS(a, u(5), u(6), b, ctrl); !ok || prev != u(5) {
+		t.Fatalf("DCSS with matching words: got (%v, %v)", prev, ok)
+	}
+	if prev, ok := mcas.DCSS(a, u(5), u(7), b, ctrl); ok || prev != u(6) {
+		t.Fatalf("DCSS with a stale expA: got (%v, %v)", prev, ok)
+	}
+	if prev, ok := mcas.DCSS(a, u(6), u(7), b, u(2)); ok || prev != u(6) {
+		t.Fatalf("DCSS with a stale expB: got (%v, %v)", prev, ok)
+	}
+	if got := mcas.DCSSLoad(a); got != u(6) {
+		t.Fatalf("DCSSLoad: got %v, want 6", got)
+	}
+	if *b != ctrl {
+		t.Fatalf("DCSS wrote the control word: %v", *b)
+	}
+}
+
+// TestDCSS_ClosedControlStopsWriters increments a counter with DCSS while the
+// control word says open. Once the closer has switched it to closed, the
+// counter it reads must never change again.
+func TestDCSS_ClosedControlStopsWriters(t *testing.T) {
+	const workers = 6
+	open, closed := u(0), u(1)
+	for range 10 {
+		a, b := dwcas.New(0, 0), dwcas.New(0, 0)
+		var successes atomic.Int64
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for range workers {
+			go func() {
+				defer wg.Done()
+				for {
+					v := mcas.DCSSLoad(a)
+					_, ok := mcas.DCSS(a, v, u(v.Lo+1), b, open)
+					if ok {
+						successes.Add(1)
+					} else if b.Load() == closed {
+						return
+					}
+					runtime.Gosched()
+				}
+			}()
+		}
+		for successes.Load() < 100 {
+			runtime.Gosched()
+		}
+		if _, ok := b.AcqRel(open, closed); !ok {
+			t.Fatalf("closing the control word failed")
+		}
+		frozen := mcas.DCSSLoad(a)
+		wg.Wait()
+		if got := mcas.DCSSLoad(a); got != frozen {
+			t.Fatalf("counter moved from %d to %d after close", frozen.Lo, got.Lo)
+		}
+		if got := uint64(successes.Load()); got != frozen.Lo {
+			t.Fatalf("counter %d, but %d DCSS operations succeeded", frozen.Lo, got)
+		}
+	}
+}
+
+func TestDCSS_WithCAS(t *testing.T) {
+	// DCSS and CAS on the same cells help each other.
+	cells := dwcas.NewSlice(2)
+	ctrl := dwcas.New(0, 0)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	iters := 2000
+	go func() {
+		defer wg.Done()
+		for range iters {
+			for {
+				v := mcas.DCSSLoad(&cells[0])
+				if _, ok := mcas.DCSS(&cells[0], v, u(v.Lo+1), ctrl, u(0)); ok {
+					break
+				}
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range iters {
+			for {
+				v0, v1 := mcas.Read(&cells[0]), mcas.Read(&cells[1])
+				if mcas.CAS([]mcas.Entry{
+					{Addr: &cells[0], Old: v0, New: u(v0.Lo + 1)},
+					{Addr: &cells[1], Old: v1, New: u(v1.Lo + 1)},
+				}) {
+					break
+				}
+			}
+		}
+	}()
+	wg.Wait()
+	if c0, c1 := mcas.Read(&cells[0]), mcas.Read(&cells[1]); c0.Lo != uint64(2*iters) || c1.Lo != uint64(iters) {
+		t.Fatalf("cells %d, %d; want %d, %d", c0.Lo, c1.Lo, 2*iters, iters)
+	}
+}
+
+func TestDCSS_ModelControlRace(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	_, err := model.Explore(model.Config{Executions: 1000}, func(mt *model.T) {
+		a, b := dwcas.New(0, 0), dwcas.New(0, 0)
+		var swapped, closed bool
+		w := mt.Go(func() {
+			_, swapped = mcas.DCSS(a, u(0), u(1), b, u(0))
+		})
+		c := mt.Go(func() {
+			_, closed = b.AcqRel(u(0), u(1))
+			if closed {
+				// Whatever a holds once the control word is closed is
+				// final.
+				v := mcas.DCSSLoad(a)
+				w.Join()
+				mt.Assert(mcas.DCSSLoad(a) == v, "a changed after close")
+			}
+		})
+		w.Join()
+		c.Join()
+		got := mcas.DCSSLoad(a)
+		mt.Assert(got.Lo == 1 == swapped, "a=%d swapped=%v", got.Lo, swapped)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
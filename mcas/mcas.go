@@ -0,0 +1,254 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mcas provides a lock-free multi-word compare-and-swap over
+// [dwcas.Uint128] cells.
+//
+// [CAS] atomically replaces the values of any number of cells provided each
+// holds its expected value. It follows Harris, Fraser and Pratt: the operation
+// is described by a descriptor, a reference to which is installed in each cell
+// in address order using a restricted double-compare single-swap (RDCSS) that
+// only succeeds while the operation is undecided. Once every cell holds the
+// reference the operation succeeds; a cell with an unexpected value makes it
+// fail. The references are then replaced with the new or the old values.
+// Any goroutine that meets a reference helps that operation to finish, so no
+// goroutine waits for another.
+//
+// Descriptors are recycled. A reference carries the descriptor's index and a
+// sequence number, and helpers discard what they read from a descriptor whose
+// sequence number has moved on.
+//
+// Cells used with this package must be read with [Read] and written only with
+// [CAS], because they may temporarily hold references instead of values. The
+// top two bits of Hi distinguish references from values, so values must keep
+// them clear: Hi must be below 1<<62. CAS panics otherwise.
+package mcas
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"code.hybscloud.com/dwcas"
+)
+
+const (
+	tagMCAS   = 1 << 63
+	tagRDCSS  = 1 << 62
+	tagMask   = tagMCAS | tagRDCSS
+	indexMask = ^uint64(tagMask)
+
+	// MaxHi is the largest Hi word a value may have.
+	MaxHi = 1<<62 - 1
+)
+
+func isMCAS(v dwcas.Uint128) bool  { return v.Hi&tagMask == tagMCAS }
+func isRDCSS(v dwcas.Uint128) bool { return v.Hi&tagMask == tagRDCSS }
+
+// Operation states, in the Hi word of a descriptor's status; Lo holds the
+// sequence number.
+const (
+	preparing = iota
+	undecided
+	succeeded
+	failed
+)
+
+// Entry is one cell of a multi-word CAS.
+type Entry struct {
+	Addr     *dwcas.Uint128
+	Old, New dwcas.Uint128
+}
+
+// entry is an Entry inside a recycled descriptor.
+type entry struct {
+	addr  atomic.Pointer[dwcas.Uint128]
+	oldLo atomic.Uint64
+	oldHi atomic.Uint64
+	newLo atomic.Uint64
+	newHi atomic.Uint64
+}
+
+// mdesc describes one multi-word CAS. status is the 16-byte cell {seq, state};
+// the RDCSS of each install compares against {seq, undecided}.
+type mdesc struct {
+	status  atomic.Pointer[dwcas.Uint128]
+	entries atomic.Pointer[[]entry]
+	n       atomic.Int64
+	next    atomic.Uint64
+}
+
+func (d *mdesc) link() *atomic.Uint64 { return &d.next }
+
+var mdescs = newPool[mdesc]()
+
+func checkValue(fn string, v dwcas.Uint128) {
+	if v.Hi > MaxHi {
+		panic("mcas: " + fn + ": value uses the reserved top bits of Hi")
+	}
+}
+
+// CAS atomically sets each entry's cell to New, provided every cell holds its
+// Old value, and reports whether it did. The entries may be given in any
+// order. An empty CAS succeeds.
+//
+// CAS panics if an Addr is nil or appears twice, or if a value has Hi above
+// [MaxHi].
+func CAS(entries []Entry) bool {
+	for i := range entries {
+		e := &entries[i]
+		if e.Addr == nil {
+			panic("mcas: CAS: nil address")
+		}
+		checkValue("CAS", e.Old)
+		checkValue("CAS", e.New)
+		for j := range i {
+			if entries[j].Addr == e.Addr {
+				panic("mcas: CAS: duplicate address")
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return true
+	}
+
+	i := mdescs.alloc()
+	d := mdescs.get(i)
+	s := d.prepare(entries)
+	ok := help(dwcas.Uint128{Lo: s, Hi: tagMCAS | i})
+	mdescs.put(i)
+	return ok
+}
+
+// prepare loads entries, sorted by address, into the owned descriptor d and
+// returns the sequence number of the new operation.
+func (d *mdesc) prepare(entries []Entry) uint64 {
+	st := d.status.Load()
+	if st == nil {
+		st = dwcas.New(0, failed)
+		d.status.Store(st)
+	}
+	cur := st.Load()
+	s := cur.Lo + 1
+	if _, ok := st.AcqRel(cur, dwcas.Uint128{Lo: s, Hi: preparing}); !ok {
+		panic("mcas: descriptor status changed while free")
+	}
+
+	es := d.entries.Load()
+	if es == nil || len(*es) < len(entries) {
+		grown := make([]entry, max(len(entries), 4))
+		es = &grown
+		d.entries.Store(es)
+	}
+	// Insertion sort by address: the global order is what keeps helpers
+	// from helping each other in a cycle.
+	dst := *es
+	for n, e := range entries {
+		j := n
+		for ; j > 0 && uintptrOf(dst[j-1].addr.Load()) > uintptrOf(e.Addr); j-- {
+			dst[j].set(dst[j-1].get())
+		}
+		dst[j].set(e)
+	}
+	d.n.Store(int64(len(entries)))
+
+	if _, ok := st.AcqRel(dwcas.Uint128{Lo: s, Hi: preparing}, dwcas.Uint128{Lo: s, Hi: undecided}); !ok {
+		panic("mcas: descriptor status changed while preparing")
+	}
+	return s
+}
+
+func (e *entry) set(v Entry) {
+	e.addr.Store(v.Addr)
+	e.oldLo.Store(v.Old.Lo)
+	e.oldHi.Store(v.Old.Hi)
+	e.newLo.Store(v.New.Lo)
+	e.newHi.Store(v.New.Hi)
+}
+
+func (e *entry) get() Entry {
+	return Entry{
+		Addr: e.addr.Load(),
+		Old:  dwcas.Uint128{Lo: e.oldLo.Load(), Hi: e.oldHi.Load()},
+		New:  dwcas.Uint128{Lo: e.newLo.Load(), Hi: e.newHi.Load()},
+	}
+}
+
+// help drives the operation ref refers to to completion and reports whether it
+// succeeded. The result is only meaningful to the owner: if the descriptor has
+// been recycled, help returns false without doing anything.
+func help(ref dwcas.Uint128) bool {
+	d := mdescs.get(ref.Hi & indexMask)
+	s := ref.Lo
+	st := d.status.Load()
+	var buf [8]Entry
+	es := buf[:0]
+	if p := d.entries.Load(); p != nil {
+		n := min(int(d.n.Load()), len(*p))
+		for i := range n {
+			es = append(es, (*p)[i].get())
+		}
+	}
+	if st == nil || st.Load().Lo != s {
+		return false
+	}
+
+	live := dwcas.Uint128{Lo: s, Hi: undecided}
+	if st.Load() == live {
+		outcome := uint64(succeeded)
+	install:
+		for _, e := range es {
+			for {
+				v, swapped := rdcss(st, live, e.Addr, e.Old, ref)
+				if swapped || v == ref {
+					break
+				}
+				if v == e.Old {
+					// Installed, but the operation was decided meanwhile.
+					break install
+				}
+				if isMCAS(v) {
+					help(v)
+					continue
+				}
+				outcome = failed
+				break install
+			}
+		}
+		st.AcqRel(live, dwcas.Uint128{Lo: s, Hi: outcome})
+	}
+
+	cur := st.Load()
+	if cur.Lo != s {
+		return false
+	}
+	ok := cur.Hi == succeeded
+	for _, e := range es {
+		if ok {
+			e.Addr.AcqRel(ref, e.New)
+		} else {
+			e.Addr.AcqRel(ref, e.Old)
+		}
+	}
+	return ok
+}
+
+// Read returns the value of a cell used with [CAS], helping any operation in
+// progress on it to finish first.
+func Read(addr *dwcas.Uint128) dwcas.Uint128 {
+	for {
+		v := addr.Load()
+		switch {
+		case isRDCSS(v):
+			complete(v)
+		case isMCAS(v):
+			help(v)
+		default:
+			return v
+		}
+	}
+}
+
+func uintptrOf(p *dwcas.Uint128) uintptr {
+	return uintptr(unsafe.Pointer(p))
+}
@@ -0,0 +1,250 @@
+package mcas_test
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/mcas"
+	"code.hybscloud.com/dwcas/model"
+)
+
+func u(lo uint64) dwcas.Uint128 { return dwcas.Uint128{Lo: lo} }
+
+func TestCAS_Sequential(t *testing.T) {
+	cells := dwcas.NewSlice(3)
+	if !mcas.CAS(nil) {
+		t.Fatalf("empty CAS failed")
+	}
+	ok := mcas.CAS([]mcas.Entry{
+		{Addr: &cells[2], Old: u(0), New: u(3)},
+		{Addr: &cells[0], Old: u(0), New: u(1)},
+		{Addr: &cells[1], Old: u(0), New: dwcas.Uint128{Lo: 2, Hi: mcas.MaxHi}},
+	})
+	if !ok {
+		t.Fatalf("CAS on expected values failed")
+	}
+	want := []dwcas.Uint128{u(1), {Lo: 2, Hi: mcas.MaxHi}, u(3)}
+	for i := range cells {
+		if got := mcas.Read(&cells[i]); got != want[i] {
+			t.Fatalf("cell %d: got %v, want %v", i, got, want[i])
+		}
+	}
+	if mcas.CAS([]mcas.Entry{
+		{Addr: &cells[0], Old: u(1), New: u(10)},
+		{Addr: &cells[2], Old: u(4), New: u(30)},
+	}) {
+		t.Fatalf("CAS with a stale old value succeeded")
+	}
+	for i := range cells {
+		if got := mcas.Read(&cells[i]); got != want[i] {
+			t.Fatalf("failed CAS changed cell %d: got %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestCAS_Panics(t *testing.T) {
+	cells := dwcas.NewSlice(2)
+	for name, es := range map[string][]mcas.Entry{
+		"nil":       {{Addr: nil}},
+		"duplicate": {{Addr: &cells[0]}, {Addr: &cells[1]}, {Addr: &cells[0]}},
+		"reserved":  {{Addr: &cells[0], New: dwcas.Uint128{Hi: mcas.MaxHi + 1}}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", name)
+				}
+			}()
+			mcas.CAS(es)
+		}()
+	}
+}
+
+func TestCAS_RecyclesDescriptors(t *testing.T) {
+	cells := dwcas.NewSlice(4)
+	es := make([]mcas.Entry, len(cells))
+	n := uint64(0)
+	allocs := testing.AllocsPerRun(1000, func() {
+		for i := range es {
+			es[i] = mcas.Entry{Addr: &cells[i], Old: u(n), New: u(n + 1)}
+		}
+		if !mcas.CAS(es) {
+			t.Fatalf("CAS failed at %d", n)
+		}
+		n++
+	})
+	if allocs != 0 {
+		t.Fatalf("steady-state CAS allocated %v times per call", allocs)
+	}
+}
+
+// TestCAS_TransfersConserveTotal moves amounts between random triples of
+// accounts while auditors take snapshots; every snapshot that a no-op CAS
+// confirms as atomic must show the initial total.
+func TestCAS_TransfersConserveTotal(t *testing.T) {
+	const (
+		accounts = 16
+		initial  = 1000
+		workers  = 6
+		auditors = 2
+	)
+	iters := 5000
+	if testing.Short() {
+		iters = 500
+	}
+	cells := dwcas.NewSlice(accounts)
+	for i := range cells {
+		cells[i] = u(initial)
+	}
+
+	var stop atomic.Bool
+	var audits atomic.Int64
+	var aw sync.WaitGroup
+	aw.Add(auditors)
+	for range auditors {
+		go func() {
+			defer aw.Done()
+			es := make([]mcas.Entry, accounts)
+			for !stop.Load() {
+				total := uint64(0)
+				for i := range cells {
+					v := mcas.Read(&cells[i])
+					es[i] = mcas.Entry{Addr: &cells[i], Old: v, New: v}
+					total += v.Lo
+				}
+				if mcas.CAS(es) {
+					audits.Add(1)
+					if total != accounts*initial {
+						t.Errorf("confirmed snapshot total %d, want %d", total, accounts*initial)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	var ww sync.WaitGroup
+	ww.Add(workers)
+	for w := range workers {
+		go func() {
+			defer ww.Done()
+			r := rand.New(rand.NewPCG(uint64(w), 7))
+			for range iters {
+				p := r.Perm(accounts)[:3]
+				a, b, c := &cells[p[0]], &cells[p[1]], &cells[p[2]]
+				for {
+					va, vb, vc := mcas.Read(a), mcas.Read(b), mcas.Read(c)
+					amt := min(va.Lo, 10)
+					if mcas.CAS([]mcas.Entry{
+						{Addr: a, Old: va, New: u(va.Lo - amt)},
+						{Addr: b, Old: vb, New: u(vb.Lo + amt/2)},
+						{Addr: c, Old: vc, New: u(vc.Lo + amt - amt/2)},
+					}) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	ww.Wait()
+	stop.Store(true)
+	aw.Wait()
+
+	total := uint64(0)
+	for i := range cells {
+		total += mcas.Read(&cells[i]).Lo
+	}
+	if total != accounts*initial {
+		t.Fatalf("final total %d, want %d", total, accounts*initial)
+	}
+}
+
+// TestCAS_IncrementsAreNotLost has every worker increment four random cells
+// at once; the cell sum must equal four times the successful operations.
+func TestCAS_IncrementsAreNotLost(t *testing.T) {
+	const (
+		n       = 8
+		workers = 8
+	)
+	iters := 4000
+	if testing.Short() {
+		iters = 400
+	}
+	cells := dwcas.NewSlice(n)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := range workers {
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(uint64(w), 11))
+			es := make([]mcas.Entry, 4)
+			for range iters {
+				p := r.Perm(n)
+				for {
+					for i := range es {
+						v := mcas.Read(&cells[p[i]])
+						es[i] = mcas.Entry{Addr: &cells[p[i]], Old: v, New: u(v.Lo + 1)}
+					}
+					if mcas.CAS(es) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	sum := uint64(0)
+	for i := range cells {
+		sum += mcas.Read(&cells[i]).Lo
+	}
+	if sum != 4*workers*uint64(iters) {
+		t.Fatalf("sum %d, want %d", sum, 4*workers*iters)
+	}
+}
+
+func TestCAS_ModelOverlappingPairs(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	_, err := model.Explore(model.Config{Executions: 1000}, func(mt *model.T) {
+		cells := dwcas.NewSlice(3)
+		var okA, okB bool
+		// Both operations expect the initial value of cell 1, so at most
+		// one of them can succeed.
+		a := mt.Go(func() {
+			okA = mcas.CAS([]mcas.Entry{
+				{Addr: &cells[0], Old: u(0), New: u(1)},
+				{Addr: &cells[1], Old: u(0), New: u(1)},
+			})
+		})
+		b := mt.Go(func() {
+			okB = mcas.CAS([]mcas.Entry{
+				{Addr: &cells[2], Old: u(0), New: u(2)},
+				{Addr: &cells[1], Old: u(0), New: u(2)},
+			})
+		})
+		r := mt.Go(func() {
+			// A reader never sees a half-applied operation.
+			v0, v1 := mcas.Read(&cells[0]), mcas.Read(&cells[1])
+			mt.Assert(v0.Lo == 0 || v1.Lo == 1, "saw cell0=%d cell1=%d", v0.Lo, v1.Lo)
+		})
+		a.Join()
+		b.Join()
+		r.Join()
+		mt.Assert(okA != okB, "okA=%v okB=%v, want exactly one", okA, okB)
+		want := []uint64{0, 2, 2}
+		if okA {
+			want = []uint64{1, 1, 0}
+		}
+		for i := range cells {
+			got := mcas.Read(&cells[i]).Lo
+			mt.Assert(got == want[i], "cell %d = %d, want %d", i, got, want[i])
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
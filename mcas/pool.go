@@ -0,0 +1,81 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mcas
+
+import (
+	"sync/atomic"
+
+	"code.hybscloud.com/dwcas"
+)
+
+const (
+	chunkBits = 10
+	chunkSize = 1 << chunkBits
+	maxChunks = 1 << 12
+)
+
+// pooled is implemented by descriptor types; link is the free-list next field.
+type pooled[T any] interface {
+	*T
+	link() *atomic.Uint64
+}
+
+// pool is a growable table of descriptors addressed by index, with a
+// lock-free free list. Descriptors are never freed, so an index taken from a
+// cell always names valid memory; sequence numbers inside each descriptor tell
+// a stale reference from a live one.
+type pool[T any, P pooled[T]] struct {
+	chunks [maxChunks]atomic.Pointer[[chunkSize]T]
+	n      atomic.Uint64
+	// free is a Treiber stack head: Lo is index+1 (0 when empty), Hi a tag
+	// that changes on every pop and push.
+	free *dwcas.Uint128
+}
+
+func newPool[T any, P pooled[T]]() *pool[T, P] {
+	return &pool[T, P]{free: dwcas.New(0, 0)}
+}
+
+// get returns the descriptor at index i, which must have been allocated.
+func (p *pool[T, P]) get(i uint64) *T {
+	return &p.chunks[i>>chunkBits].Load()[i&(chunkSize-1)]
+}
+
+// alloc returns the index of an unused descriptor.
+func (p *pool[T, P]) alloc() uint64 {
+	head := p.free.Load()
+	for head.Lo != 0 {
+		i := head.Lo - 1
+		next := P(p.get(i)).link().Load()
+		prev, ok := p.free.AcqRel(head, dwcas.Uint128{Lo: next, Hi: head.Hi + 1})
+		if ok {
+			return i
+		}
+		head = prev
+	}
+	i := p.n.Add(1) - 1
+	c := i >> chunkBits
+	if c >= maxChunks {
+		panic("mcas: too many descriptors in use")
+	}
+	if p.chunks[c].Load() == nil {
+		p.chunks[c].CompareAndSwap(nil, new([chunkSize]T))
+	}
+	return i
+}
+
+// put returns descriptor i to the free list.
+func (p *pool[T, P]) put(i uint64) {
+	link := P(p.get(i)).link()
+	head := p.free.Load()
+	for {
+		link.Store(head.Lo)
+		prev, ok := p.free.AcqRel(head, dwcas.Uint128{Lo: i + 1, Hi: head.Hi + 1})
+		if ok {
+			return
+		}
+		head = prev
+	}
+}
@@ -0,0 +1,133 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mcas
+
+import (
+	"sync/atomic"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// rdesc describes one restricted double-compare single-swap: replace old with
+// new at addr, provided ctrl holds exp.
+//
+// The fields are rewritten on reuse, so every field is atomic and a helper
+// validates what it read against seq, which is odd while the owner rewrites
+// the descriptor. decision holds seq<<2 | outcome; the first completer to
+// evaluate the control word fixes the outcome there, and every completer then
+// applies that same outcome.
+type rdesc struct {
+	seq      atomic.Uint64
+	ctrl     atomic.Pointer[dwcas.Uint128]
+	addr     atomic.Pointer[dwcas.Uint128]
+	expLo    atomic.Uint64
+	expHi    atomic.Uint64
+	oldLo    atomic.Uint64
+	oldHi    atomic.Uint64
+	newLo    atomic.Uint64
+	newHi    atomic.Uint64
+	decision atomic.Uint64
+	next     atomic.Uint64
+}
+
+func (r *rdesc) link() *atomic.Uint64 { return &r.next }
+
+// Outcomes recorded in rdesc.decision.
+const (
+	pending    = 0
+	matched    = 1
+	mismatched = 2
+)
+
+var rdescs = newPool[rdesc]()
+
+// rdcssOp is a validated copy of an rdesc.
+type rdcssOp struct {
+	ctrl, addr    *dwcas.Uint128
+	exp, old, new dwcas.Uint128
+}
+
+// rdcss replaces old with new at addr if ctrl holds exp at the linearization
+// point. prev is the value found at addr: old if the descriptor was installed,
+// in which case swapped tells whether new was written. Otherwise prev is the
+// conflicting value, which may be an MCAS reference but never an RDCSS one.
+func rdcss(ctrl *dwcas.Uint128, exp dwcas.Uint128, addr *dwcas.Uint128, old, new dwcas.Uint128) (prev dwcas.Uint128, swapped bool) {
+	i := rdescs.alloc()
+	r := rdescs.get(i)
+	s := r.seq.Add(1) // odd: rewriting
+	r.ctrl.Store(ctrl)
+	r.addr.Store(addr)
+	r.expLo.Store(exp.Lo)
+	r.expHi.Store(exp.Hi)
+	r.oldLo.Store(old.Lo)
+	r.oldHi.Store(old.Hi)
+	r.newLo.Store(new.Lo)
+	r.newHi.Store(new.Hi)
+	s++
+	r.decision.Store(s << 2)
+	r.seq.Store(s)
+	ref := dwcas.Uint128{Lo: s, Hi: tagRDCSS | i}
+
+	for {
+		v, ok := addr.AcqRel(old, ref)
+		if ok {
+			break
+		}
+		if isRDCSS(v) {
+			complete(v)
+			continue
+		}
+		rdescs.put(i)
+		return v, false
+	}
+	complete(ref)
+	// complete only returns once ref has left addr, so the outcome is fixed.
+	swapped = r.decision.Load() == s<<2|matched
+	rdescs.put(i)
+	return old, swapped
+}
+
+// complete finishes the RDCSS that ref refers to, removing ref from its cell.
+// It does nothing if the RDCSS has already been completed.
+func complete(ref dwcas.Uint128) {
+	r := rdescs.get(ref.Hi & indexMask)
+	s := ref.Lo
+	op := rdcssOp{
+		ctrl: r.ctrl.Load(),
+		addr: r.addr.Load(),
+		exp:  dwcas.Uint128{Lo: r.expLo.Load(), Hi: r.expHi.Load()},
+		old:  dwcas.Uint128{Lo: r.oldLo.Load(), Hi: r.oldHi.Load()},
+		new:  dwcas.Uint128{Lo: r.newLo.Load(), Hi: r.newHi.Load()},
+	}
+	if r.seq.Load() != s {
+		// Reused: the owner completed this RDCSS before releasing it.
+		return
+	}
+	outcome := uint64(mismatched)
+	if op.ctrl.Load() == op.exp {
+		outcome = matched
+	}
+	r.decision.CompareAndSwap(s<<2|pending, s<<2|outcome)
+	d := r.decision.Load()
+	if d>>2 != s&(1<<62-1) {
+		return
+	}
+	if d&3 != matched {
+		op.addr.AcqRel(ref, op.old)
+		return
+	}
+	if _, ok := op.addr.AcqRel(ref, op.new); !ok || !isMCAS(op.new) {
+		return
+	}
+	// The outcome was fixed while the MCAS was undecided, but it may have
+	// been decided since, and its cleanup may already have passed this cell.
+	// A failed MCAS would then leave its reference behind, so restore the
+	// old value here. A succeeded MCAS cannot have passed the cell: it needed
+	// the reference just installed.
+	st := op.ctrl.Load()
+	if st != op.exp && st != (dwcas.Uint128{Lo: op.exp.Lo, Hi: succeeded}) {
+		op.addr.AcqRel(op.new, op.old)
+	}
+}
@@ -0,0 +1,44 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"unsafe"
+
+	"code.hybscloud.com/dwcas/internal/hook"
+)
+
+// backend turns dwcas operations into scheduling points and, in the weak
+// memory model, performs them on simulated memory.
+type backend struct{}
+
+func (backend) Cas128(ptr *uint64, oldLo, oldHi, newLo, newHi uint64, order hook.Order) (prevLo, prevHi uint64, swapped, handled bool) {
+	e := current()
+	if e == nil {
+		return 0, 0, false, false
+	}
+	e.yield()
+	if e.mem == nil {
+		return 0, 0, false, false
+	}
+	// dwcas CAS methods always fail with relaxed ordering.
+	acquire := order == hook.Acquire || order == hook.AcqRel
+	release := order == hook.Release || order == hook.AcqRel
+	prevLo, prevHi, swapped = e.cas(unsafe.Pointer(ptr), true, oldLo, oldHi, newLo, newHi, acquire, release, false)
+	return prevLo, prevHi, swapped, true
+}
+
+func (backend) Barrier(fence hook.Fence) (handled bool) {
+	e := current()
+	if e == nil {
+		return false
+	}
+	e.yield()
+	if e.mem == nil {
+		return false
+	}
+	e.mem.fence(e.cur.mem, fence == hook.FenceAcquire, fence == hook.FenceRelease, fence == hook.FenceFull)
+	return true
+}
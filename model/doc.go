@@ -0,0 +1,48 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package model is a deterministic interleaving explorer for algorithms built on
+// dwcas.
+//
+// Hardware stress tests rarely hit the interleavings that break lock-free code.
+// [Explore] instead runs a test body many times under a scheduler that allows
+// exactly one managed goroutine to run at a time and decides, at every
+// scheduling point, which goroutine runs next. Interleavings are chosen either
+// at random from a seed or systematically (depth-first over every choice), and
+// every failure carries the seed and the exact schedule needed to replay it.
+//
+// # Scheduling points
+//
+// When dwcas is built with `-tags=dwcas_model`, every [dwcas.Uint128] CAS method
+// and every manual barrier ([dwcas.BarrierAcquire], [dwcas.BarrierRelease],
+// [dwcas.BarrierFull]) called from a managed goroutine becomes a scheduling
+// point. [T.Yield] and [Handle.Join] are scheduling points in every build, so the
+// scheduler itself can be exercised without the tag. [Instrumented] reports
+// whether the tag is in effect.
+//
+// # Rules for test bodies
+//
+//   - Start goroutines with [T.Go], never with the go statement.
+//   - Synchronize managed goroutines only through dwcas operations, [T.Yield] and
+//     [Handle.Join]. Blocking on channels, mutexes or other runtime primitives
+//     stalls the scheduler.
+//   - Do not call dwcas from unmanaged goroutines while [Explore] runs.
+//   - The body must be deterministic given the schedule: no wall-clock time, no
+//     unseeded randomness, no map iteration order.
+//
+// # Weak memory
+//
+// With Config.Memory set to [Weak], dwcas CAS methods, dwcas barriers and the
+// companion [Uint64] atomics run on simulated memory that models a relaxed,
+// arm64-like memory model. A load may then observe a stale value unless the
+// orderings in use forbid it, so choosing Relaxed where Acquire was needed fails
+// an assertion even on an amd64 host. Once a location has been touched by a
+// simulated operation, access it only through dwcas or [Uint64] operations
+// until the execution ends.
+//
+// Only one [Explore] call runs at a time; concurrent calls are serialized.
+//
+// Spin loops must pass through a scheduling point on every iteration. A loop
+// that spins on a plain load never gives the scheduler control back.
+package model
@@ -0,0 +1,216 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// active is the execution currently driven by [Explore], if any.
+var active atomic.Pointer[execution]
+
+// abortSignal unwinds a managed goroutine when its execution ends early.
+type abortSignal struct{}
+
+// thread is a managed goroutine.
+type thread struct {
+	id      int
+	wake    chan struct{}
+	done    bool
+	joining *thread
+	mem     *threadView
+}
+
+func (th *thread) runnable() bool {
+	return !th.done && (th.joining == nil || th.joining.done)
+}
+
+// execution is a single run of a test body.
+//
+// Exactly one of the scheduler and the managed goroutines runs at any time;
+// control is handed over through wake and yielded, which also orders every
+// access to the fields below.
+type execution struct {
+	cfg     *Config
+	c       chooser
+	threads []*thread
+	cur     *thread
+	yielded chan struct{}
+	mem     *memory
+
+	steps       int
+	preemptions int
+	trace       []int
+
+	aborting  bool
+	truncated bool
+	failure   string
+}
+
+func newExecution(cfg *Config, c chooser) *execution {
+	e := &execution{cfg: cfg, c: c, yielded: make(chan struct{})}
+	if cfg.Memory == Weak {
+		e.mem = newMemory()
+	}
+	return e
+}
+
+// choose resolves a choice among n alternatives and records it in the trace.
+// Choices with a single alternative are not recorded.
+func (e *execution) choose(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	i, ok := e.c.choose(n)
+	if !ok {
+		e.fail(fmt.Sprintf("nondeterministic body: choice %d has %d alternatives on replay", len(e.trace), n))
+		return 0
+	}
+	e.trace = append(e.trace, i)
+	return i
+}
+
+// chooseOrAbort is choose for managed goroutines: it stops the calling
+// goroutine if the choice failed the execution.
+func (e *execution) chooseOrAbort(n int) int {
+	i := e.choose(n)
+	if e.failure != "" {
+		panic(abortSignal{})
+	}
+	return i
+}
+
+// spawn creates a managed goroutine. In the weak memory model the new goroutine
+// starts with everything its parent has observed.
+func (e *execution) spawn(fn func()) *thread {
+	th := &thread{id: len(e.threads), wake: make(chan struct{})}
+	if e.mem != nil {
+		var parent *threadView
+		if e.cur != nil {
+			parent = e.cur.mem
+		}
+		th.mem = newThreadView(parent)
+	}
+	e.threads = append(e.threads, th)
+	go func() {
+		<-th.wake
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(abortSignal); !ok {
+					e.fail(fmt.Sprintf("goroutine %d panicked: %v\n%s", th.id, r, debug.Stack()))
+				}
+			}
+			th.done = true
+			e.yielded <- struct{}{}
+		}()
+		if e.aborting {
+			return
+		}
+		fn()
+	}()
+	return th
+}
+
+func (e *execution) fail(msg string) {
+	if e.failure == "" {
+		e.failure = msg
+	}
+}
+
+// yield hands control back to the scheduler from the current goroutine and
+// blocks until the scheduler selects it again.
+func (e *execution) yield() {
+	th := e.cur
+	e.yielded <- struct{}{}
+	<-th.wake
+	if e.aborting {
+		panic(abortSignal{})
+	}
+}
+
+// pick selects the next goroutine to run, or nil if none is runnable.
+func (e *execution) pick() *thread {
+	var runnable []*thread
+	for _, th := range e.threads {
+		if th.runnable() {
+			runnable = append(runnable, th)
+		}
+	}
+	if len(runnable) == 0 {
+		return nil
+	}
+	cur := e.cur
+	curRunnable := cur != nil && cur.runnable()
+	if curRunnable && e.cfg.PreemptionBound > 0 && e.preemptions >= e.cfg.PreemptionBound {
+		return cur
+	}
+	next := runnable[e.choose(len(runnable))]
+	if curRunnable && next != cur {
+		e.preemptions++
+	}
+	return next
+}
+
+func (e *execution) run(body func(t *T)) {
+	active.Store(e)
+	defer active.Store(nil)
+
+	t := &T{e: e}
+	e.spawn(func() { body(t) })
+	for e.failure == "" {
+		next := e.pick()
+		if e.failure != "" {
+			break
+		}
+		if next == nil {
+			if blocked := e.blocked(); blocked != "" {
+				e.fail("deadlock: " + blocked)
+			}
+			break
+		}
+		if e.steps >= e.cfg.MaxSteps {
+			e.truncated = true
+			break
+		}
+		e.steps++
+		e.cur = next
+		next.wake <- struct{}{}
+		<-e.yielded
+	}
+	e.teardown()
+}
+
+// blocked describes goroutines that have not finished, or returns "".
+func (e *execution) blocked() string {
+	s := ""
+	for _, th := range e.threads {
+		if th.done {
+			continue
+		}
+		if s != "" {
+			s += ", "
+		}
+		s += fmt.Sprintf("goroutine %d waits for goroutine %d", th.id, th.joining.id)
+	}
+	return s
+}
+
+// teardown unwinds every goroutine that has not finished.
+func (e *execution) teardown() {
+	e.aborting = true
+	for _, th := range e.threads {
+		for !th.done {
+			e.cur = th
+			th.wake <- struct{}{}
+			<-e.yielded
+		}
+	}
+}
+
+func current() *execution {
+	return active.Load()
+}
@@ -0,0 +1,177 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"sync"
+
+	"code.hybscloud.com/dwcas/internal/hook"
+)
+
+// Strategy selects how [Explore] chooses interleavings.
+type Strategy uint8
+
+const (
+	// Random picks uniformly among the runnable goroutines at each scheduling
+	// point. Execution i uses seed Config.Seed+i.
+	Random Strategy = iota
+
+	// Exhaustive enumerates every interleaving depth-first, bounded by
+	// Config.Executions, Config.MaxSteps and Config.PreemptionBound.
+	Exhaustive
+)
+
+const (
+	// DefaultExecutions is used when Config.Executions is zero.
+	DefaultExecutions = 1000
+
+	// DefaultMaxSteps is used when Config.MaxSteps is zero.
+	DefaultMaxSteps = 10000
+)
+
+// Config controls an [Explore] run.
+type Config struct {
+	// Strategy selects random or exhaustive exploration.
+	Strategy Strategy
+
+	// Seed is the seed of the first random execution.
+	Seed int64
+
+	// Executions bounds the number of executions. Zero means
+	// DefaultExecutions. Exhaustive exploration may stop earlier once every
+	// interleaving has been visited.
+	Executions int
+
+	// MaxSteps bounds the scheduling points of a single execution. Zero means
+	// DefaultMaxSteps. Executions that reach the bound are abandoned and
+	// counted in Report.Truncated; they are not failures.
+	MaxSteps int
+
+	// PreemptionBound, when positive, limits how many times per execution the
+	// scheduler may switch away from a goroutine that could have continued.
+	// Small bounds keep exhaustive exploration tractable and still find most
+	// concurrency bugs.
+	PreemptionBound int
+
+	// Memory selects the simulated memory model. The zero value is
+	// SequentiallyConsistent.
+	Memory Memory
+
+	// Replay, when non-nil, runs exactly one execution that follows the given
+	// schedule (see Failure.Schedule) and ignores Strategy, Seed and
+	// Executions.
+	Replay []int
+}
+
+// Report summarizes an [Explore] run.
+type Report struct {
+	// Executions is the number of executions that ran.
+	Executions int
+
+	// Truncated is the number of executions abandoned at Config.MaxSteps.
+	Truncated int
+
+	// Exhausted reports whether exhaustive exploration visited every
+	// interleaving within the configured bounds.
+	Exhausted bool
+}
+
+// Failure describes an execution that failed an assertion, panicked or
+// deadlocked.
+type Failure struct {
+	// Message describes what went wrong.
+	Message string
+
+	// Execution is the zero-based index of the failing execution.
+	Execution int
+
+	// Seed reproduces the failure with Config{Strategy: Random, Seed: Seed,
+	// Executions: 1}. It is only meaningful for the Random strategy.
+	Seed int64
+
+	// Schedule is the sequence of scheduler choices of the failing execution.
+	// Pass it as Config.Replay to reproduce the failure under any strategy.
+	Schedule []int
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("model: execution %d failed: %s (seed %d, schedule %v)",
+		f.Execution, f.Message, f.Seed, f.Schedule)
+}
+
+// Instrumented reports whether dwcas was built with `-tags=dwcas_model`, that is,
+// whether dwcas CAS methods and barriers are scheduling points.
+func Instrumented() bool {
+	return hook.Enabled
+}
+
+var exploreMu sync.Mutex
+
+// Explore runs body repeatedly under the deterministic scheduler.
+//
+// body runs on the first managed goroutine and may start more with [T.Go]. An
+// execution ends when every managed goroutine has returned. Explore stops at the
+// first failing execution and returns it as a *[Failure].
+func Explore(cfg Config, body func(t *T)) (Report, error) {
+	exploreMu.Lock()
+	defer exploreMu.Unlock()
+
+	if cfg.Executions <= 0 {
+		cfg.Executions = DefaultExecutions
+	}
+	if cfg.MaxSteps <= 0 {
+		cfg.MaxSteps = DefaultMaxSteps
+	}
+
+	hook.Install(backend{})
+	defer hook.Install(nil)
+
+	var rep Report
+	var ex *dfs
+	if cfg.Replay == nil && cfg.Strategy == Exhaustive {
+		ex = &dfs{}
+	}
+	for i := 0; ; i++ {
+		if cfg.Replay != nil && i > 0 {
+			break
+		}
+		if i >= cfg.Executions {
+			break
+		}
+
+		seed := cfg.Seed + int64(i)
+		var c chooser
+		switch {
+		case cfg.Replay != nil:
+			c = &replay{schedule: cfg.Replay}
+		case ex != nil:
+			ex.rewind()
+			c = ex
+		default:
+			c = newRandom(seed)
+		}
+
+		e := newExecution(&cfg, c)
+		e.run(body)
+		rep.Executions++
+		if e.truncated {
+			rep.Truncated++
+		}
+		if e.failure != "" {
+			return rep, &Failure{
+				Message:   e.failure,
+				Execution: i,
+				Seed:      seed,
+				Schedule:  e.trace,
+			}
+		}
+		if ex != nil && !ex.advance() {
+			rep.Exhausted = true
+			break
+		}
+	}
+	return rep, nil
+}
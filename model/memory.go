@@ -0,0 +1,222 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"maps"
+	"unsafe"
+)
+
+// Memory selects the memory model simulated by [Explore].
+type Memory uint8
+
+const (
+	// SequentiallyConsistent runs dwcas operations on real memory between
+	// scheduling points. Every load observes the latest store.
+	SequentiallyConsistent Memory = iota
+
+	// Weak simulates a relaxed, arm64-like memory model for dwcas CAS methods,
+	// dwcas barriers and [Uint64] operations. A load may observe any store that
+	// the loading goroutine has not yet been ordered after, so an ordering that
+	// is too weak shows up as a stale read on any host.
+	//
+	// The model is view-based in the style of C11: every store carries the
+	// view its writer published, acquire operations and fences import views,
+	// and read-modify-write operations always read the latest store. It admits
+	// a superset of arm64 behaviours. In particular it does not model
+	// multi-copy atomicity or dependency ordering, and it treats
+	// [dwcas.BarrierRelease] as a release fence even though DMB ISHST does not
+	// order earlier loads.
+	Weak
+)
+
+// Order is the memory ordering of a [Uint64] operation.
+type Order uint8
+
+const (
+	Relaxed Order = iota
+	Acquire
+	Release
+	AcqRel
+	SeqCst
+)
+
+func (o Order) acquires() bool { return o == Acquire || o == AcqRel || o == SeqCst }
+func (o Order) releases() bool { return o == Release || o == AcqRel || o == SeqCst }
+
+// view maps each location to the index of the oldest store a goroutine may
+// still observe there.
+type view map[unsafe.Pointer]int
+
+func (v view) join(w view) {
+	for k, i := range w {
+		if i > v[k] {
+			v[k] = i
+		}
+	}
+}
+
+// store is one entry of a location's modification order.
+type store struct {
+	lo, hi uint64
+	view   view
+}
+
+// location is the modification order of one simulated word or word pair.
+type location struct {
+	wide    bool
+	history []store
+}
+
+// memory is the simulated state of one execution.
+type memory struct {
+	locs map[unsafe.Pointer]*location
+	sc   view
+}
+
+func newMemory() *memory {
+	return &memory{locs: map[unsafe.Pointer]*location{}, sc: view{}}
+}
+
+// threadView is the per-goroutine state of the simulated memory.
+type threadView struct {
+	cur view // oldest observable store per location
+	acq view // imported by the next acquire fence
+	rel view // published by relaxed stores after a release fence
+}
+
+func newThreadView(parent *threadView) *threadView {
+	tv := &threadView{cur: view{}, acq: view{}, rel: view{}}
+	if parent != nil {
+		tv.cur.join(parent.cur)
+	}
+	return tv
+}
+
+// location returns the simulated location at ptr. On first use it is seeded
+// with the current contents of real memory as an initial store visible to
+// every goroutine.
+func (m *memory) location(ptr unsafe.Pointer, wide bool) *location {
+	if l, ok := m.locs[ptr]; ok {
+		return l
+	}
+	init := store{lo: *(*uint64)(ptr), view: view{}}
+	if wide {
+		init.hi = *(*uint64)(unsafe.Add(ptr, 8))
+	}
+	l := &location{wide: wide, history: []store{init}}
+	m.locs[ptr] = l
+	return l
+}
+
+// observe records that tv read store i of l and imports its view, either
+// immediately (acquire) or at the next acquire fence.
+func (tv *threadView) observe(ptr unsafe.Pointer, l *location, i int, acquire bool) {
+	if i > tv.cur[ptr] {
+		tv.cur[ptr] = i
+	}
+	if acquire {
+		tv.cur.join(l.history[i].view)
+	} else {
+		tv.acq.join(l.history[i].view)
+	}
+}
+
+// append adds a store to the modification order of l. A release store
+// publishes the writer's whole view; a relaxed one only what the last release
+// fence published. A read-modify-write store also continues the release
+// sequence of the store it replaced.
+//
+// Real memory mirrors the latest store so that the final state is visible
+// once the execution ends.
+func (tv *threadView) append(ptr unsafe.Pointer, l *location, lo, hi uint64, release, rmw bool) {
+	i := len(l.history)
+	tv.cur[ptr] = i
+	var v view
+	if release {
+		v = maps.Clone(tv.cur)
+	} else {
+		v = maps.Clone(tv.rel)
+		v[ptr] = i
+	}
+	if rmw {
+		v.join(l.history[i-1].view)
+	}
+	l.history = append(l.history, store{lo: lo, hi: hi, view: v})
+	*(*uint64)(ptr) = lo
+	if l.wide {
+		*(*uint64)(unsafe.Add(ptr, 8)) = hi
+	}
+}
+
+func (m *memory) fence(tv *threadView, acquire, release, full bool) {
+	if acquire || full {
+		tv.cur.join(tv.acq)
+	}
+	if full {
+		tv.cur.join(m.sc)
+		m.sc = maps.Clone(tv.cur)
+	}
+	if release || full {
+		tv.rel = maps.Clone(tv.cur)
+	}
+}
+
+// load simulates a load of the location at ptr.
+func (e *execution) load(ptr unsafe.Pointer, wide, acquire bool) (lo, hi uint64) {
+	l := e.mem.location(ptr, wide)
+	tv := e.cur.mem
+	first := tv.cur[ptr]
+	i := first + e.chooseOrAbort(len(l.history)-first)
+	tv.observe(ptr, l, i, acquire)
+	s := l.history[i]
+	return s.lo, s.hi
+}
+
+// store simulates a store to the location at ptr.
+func (e *execution) store(ptr unsafe.Pointer, wide bool, lo, hi uint64, release bool) {
+	l := e.mem.location(ptr, wide)
+	e.cur.mem.append(ptr, l, lo, hi, release, false)
+}
+
+// rmw simulates a read-modify-write of the location at ptr. It always reads the
+// latest store; f computes the new value and reports whether to write it.
+func (e *execution) rmw(ptr unsafe.Pointer, wide, acquire, release bool, f func(lo, hi uint64) (uint64, uint64, bool)) (prevLo, prevHi uint64, wrote bool) {
+	l := e.mem.location(ptr, wide)
+	tv := e.cur.mem
+	last := len(l.history) - 1
+	s := l.history[last]
+	newLo, newHi, ok := f(s.lo, s.hi)
+	if !ok {
+		return s.lo, s.hi, false
+	}
+	tv.observe(ptr, l, last, acquire)
+	tv.append(ptr, l, newLo, newHi, release, true)
+	return s.lo, s.hi, true
+}
+
+// cas simulates a strong compare-and-swap. A successful CAS is a
+// read-modify-write of the latest store. A failed CAS is a load that may
+// observe any visible store whose value differs from old.
+func (e *execution) cas(ptr unsafe.Pointer, wide bool, oldLo, oldHi, newLo, newHi uint64, acquire, release, failAcquire bool) (prevLo, prevHi uint64, swapped bool) {
+	prevLo, prevHi, swapped = e.rmw(ptr, wide, acquire, release, func(lo, hi uint64) (uint64, uint64, bool) {
+		return newLo, newHi, lo == oldLo && hi == oldHi
+	})
+	if swapped {
+		return prevLo, prevHi, true
+	}
+	l := e.mem.locs[ptr]
+	tv := e.cur.mem
+	var candidates []int
+	for i := tv.cur[ptr]; i < len(l.history); i++ {
+		if s := l.history[i]; s.lo != oldLo || s.hi != oldHi {
+			candidates = append(candidates, i)
+		}
+	}
+	i := candidates[e.chooseOrAbort(len(candidates))]
+	tv.observe(ptr, l, i, failAcquire)
+	s := l.history[i]
+	return s.lo, s.hi, false
+}
@@ -0,0 +1,254 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/model"
+)
+
+func weak() model.Config {
+	return model.Config{Strategy: model.Exhaustive, Memory: model.Weak}
+}
+
+type casFunc func(p *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool)
+
+// load reads p with a CAS that writes back the value it observed. A failed CAS
+// only has relaxed ordering, so the load is the final, successful attempt.
+func load(p *dwcas.Uint128, cas casFunc) dwcas.Uint128 {
+	old := dwcas.Uint128{}
+	for {
+		prev, ok := cas(p, old, old)
+		if ok {
+			return prev
+		}
+		old = prev
+	}
+}
+
+// messagePassing publishes data through a dwcas flag cell. pub and sub are the
+// writer's publishing CAS and the reader's observing CAS.
+func messagePassing(pub, sub casFunc) func(t *model.T) {
+	return func(t *model.T) {
+		var data model.Uint64
+		flag := dwcas.New(0, 0)
+		w := t.Go(func() {
+			data.Store(42, model.Relaxed)
+			pub(flag, dwcas.Uint128{}, dwcas.Uint128{Lo: 1})
+		})
+		r := t.Go(func() {
+			if seen := load(flag, sub); seen.Lo == 1 {
+				got := data.Load(model.Relaxed)
+				t.Assert(got == 42, "flag observed but data=%d", got)
+			}
+		})
+		w.Join()
+		r.Join()
+	}
+}
+
+func relaxed(p *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool) {
+	return p.Relaxed(old, new)
+}
+func acquire(p *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool) {
+	return p.Acquire(old, new)
+}
+func release(p *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool) {
+	return p.Release(old, new)
+}
+
+func TestWeak_MessagePassingAcquireRelease(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	rep, err := model.Explore(weak(), messagePassing(release, acquire))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.Exhausted {
+		t.Fatalf("exploration not exhausted: %+v", rep)
+	}
+}
+
+func TestWeak_MessagePassingRelaxedReaderFails(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	body := messagePassing(release, relaxed)
+	if _, err := model.Explore(model.Config{Strategy: model.Exhaustive}, body); err != nil {
+		t.Fatalf("sequentially consistent memory must not expose the bug: %v", err)
+	}
+	_, err := model.Explore(weak(), body)
+	var f *model.Failure
+	if !errors.As(err, &f) {
+		t.Fatalf("expected stale read under weak memory, got %v", err)
+	}
+}
+
+func TestWeak_MessagePassingRelaxedWriterFails(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	_, err := model.Explore(weak(), messagePassing(relaxed, acquire))
+	var f *model.Failure
+	if !errors.As(err, &f) {
+		t.Fatalf("expected stale read under weak memory, got %v", err)
+	}
+}
+
+func TestWeak_BarriersRepairRelaxedCAS(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	pub := func(p *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool) {
+		dwcas.BarrierRelease()
+		return p.Relaxed(old, new)
+	}
+	sub := func(p *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool) {
+		prev, ok := p.Relaxed(old, new)
+		dwcas.BarrierAcquire()
+		return prev, ok
+	}
+	if _, err := model.Explore(weak(), messagePassing(pub, sub)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWeak_ReleaseSequenceThroughRelaxedRMW(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	// A relaxed CAS by a third goroutine continues the writer's release
+	// sequence, so an acquire reader of its value still sees the data.
+	_, err := model.Explore(weak(), func(t *model.T) {
+		var data model.Uint64
+		flag := dwcas.New(0, 0)
+		w := t.Go(func() {
+			data.Store(42, model.Relaxed)
+			flag.Release(dwcas.Uint128{}, dwcas.Uint128{Lo: 1})
+		})
+		m := t.Go(func() {
+			flag.Relaxed(dwcas.Uint128{Lo: 1}, dwcas.Uint128{Lo: 2})
+		})
+		r := t.Go(func() {
+			if seen := load(flag, acquire); seen.Lo == 2 {
+				got := data.Load(model.Relaxed)
+				t.Assert(got == 42, "release sequence broken: data=%d", got)
+			}
+		})
+		w.Join()
+		m.Join()
+		r.Join()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// storeBuffering reports whether both goroutines can read the initial value.
+func storeBuffering(fence func()) func(t *model.T) {
+	return func(t *model.T) {
+		var x, y model.Uint64
+		var r1, r2 uint64
+		a := t.Go(func() {
+			x.Store(1, model.Relaxed)
+			fence()
+			r1 = y.Load(model.Relaxed)
+		})
+		b := t.Go(func() {
+			y.Store(1, model.Relaxed)
+			fence()
+			r2 = x.Load(model.Relaxed)
+		})
+		a.Join()
+		b.Join()
+		t.Assert(r1 == 1 || r2 == 1, "store buffering: r1=%d r2=%d", r1, r2)
+	}
+}
+
+func TestWeak_StoreBufferingNeedsFullFence(t *testing.T) {
+	_, err := model.Explore(weak(), storeBuffering(func() {}))
+	var f *model.Failure
+	if !errors.As(err, &f) {
+		t.Fatalf("expected store buffering without fences, got %v", err)
+	}
+	if !model.Instrumented() {
+		return
+	}
+	if _, err := model.Explore(weak(), storeBuffering(dwcas.BarrierFull)); err != nil {
+		t.Fatalf("BarrierFull must forbid store buffering: %v", err)
+	}
+}
+
+func TestWeak_SeqCstForbidsStoreBuffering(t *testing.T) {
+	_, err := model.Explore(weak(), func(t *model.T) {
+		var x, y model.Uint64
+		var r1, r2 uint64
+		a := t.Go(func() {
+			x.Store(1, model.SeqCst)
+			r1 = y.Load(model.SeqCst)
+		})
+		b := t.Go(func() {
+			y.Store(1, model.SeqCst)
+			r2 = x.Load(model.SeqCst)
+		})
+		a.Join()
+		b.Join()
+		t.Assert(r1 == 1 || r2 == 1, "store buffering: r1=%d r2=%d", r1, r2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWeak_ReadReadCoherence(t *testing.T) {
+	_, err := model.Explore(weak(), func(t *model.T) {
+		var x model.Uint64
+		w := t.Go(func() {
+			x.Store(1, model.Relaxed)
+			x.Store(2, model.Relaxed)
+		})
+		r := t.Go(func() {
+			a := x.Load(model.Relaxed)
+			b := x.Load(model.Relaxed)
+			t.Assert(b >= a, "coherence violated: read %d then %d", a, b)
+		})
+		w.Join()
+		r.Join()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWeak_RMWAreAtomic(t *testing.T) {
+	_, err := model.Explore(weak(), func(t *model.T) {
+		var x model.Uint64
+		a := t.Go(func() { x.Add(1, model.Relaxed) })
+		b := t.Go(func() { x.Add(1, model.Relaxed) })
+		a.Join()
+		b.Join()
+		t.Assert(x.Load(model.Relaxed) == 2, "lost increment")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUint64_OutsideExplore(t *testing.T) {
+	var x model.Uint64
+	x.Store(5, model.Relaxed)
+	if got := x.Add(2, model.AcqRel); got != 7 {
+		t.Fatalf("Add: got %d, want 7", got)
+	}
+	if old := x.Swap(1, model.AcqRel); old != 7 {
+		t.Fatalf("Swap: got %d, want 7", old)
+	}
+	if !x.CompareAndSwap(1, 3, model.AcqRel) || x.CompareAndSwap(1, 4, model.AcqRel) {
+		t.Fatalf("CompareAndSwap mismatch")
+	}
+	if got := x.Load(model.Acquire); got != 3 {
+		t.Fatalf("Load: got %d, want 3", got)
+	}
+}
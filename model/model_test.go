@@ -0,0 +1,221 @@
+package model_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/model"
+)
+
+// lostUpdate is a racy read-modify-write with an explicit scheduling point
+// between the read and the write.
+func lostUpdate(t *model.T) {
+	counter := 0
+	incr := func() {
+		v := counter
+		t.Yield()
+		counter = v + 1
+	}
+	a := t.Go(incr)
+	b := t.Go(incr)
+	a.Join()
+	b.Join()
+	t.Assert(counter == 2, "lost update: counter=%d", counter)
+}
+
+func TestExplore_ExhaustiveFindsLostUpdate(t *testing.T) {
+	_, err := model.Explore(model.Config{Strategy: model.Exhaustive}, lostUpdate)
+	var f *model.Failure
+	if !errors.As(err, &f) {
+		t.Fatalf("expected *model.Failure, got %v", err)
+	}
+	if !strings.Contains(f.Message, "lost update") {
+		t.Fatalf("unexpected failure message: %q", f.Message)
+	}
+
+	_, err = model.Explore(model.Config{Replay: f.Schedule}, lostUpdate)
+	var g *model.Failure
+	if !errors.As(err, &g) {
+		t.Fatalf("replay did not reproduce the failure: %v", err)
+	}
+	if g.Message != f.Message || !slices.Equal(g.Schedule, f.Schedule) {
+		t.Fatalf("replay diverged: got (%q, %v), want (%q, %v)", g.Message, g.Schedule, f.Message, f.Schedule)
+	}
+}
+
+func TestExplore_RandomSeedReplays(t *testing.T) {
+	_, err := model.Explore(model.Config{Seed: 42}, lostUpdate)
+	var f *model.Failure
+	if !errors.As(err, &f) {
+		t.Fatalf("expected *model.Failure, got %v", err)
+	}
+
+	_, err = model.Explore(model.Config{Seed: f.Seed, Executions: 1}, lostUpdate)
+	var g *model.Failure
+	if !errors.As(err, &g) {
+		t.Fatalf("seed %d did not reproduce the failure: %v", f.Seed, err)
+	}
+	if !slices.Equal(g.Schedule, f.Schedule) {
+		t.Fatalf("seed replay diverged: got %v, want %v", g.Schedule, f.Schedule)
+	}
+}
+
+func TestExplore_ExhaustiveVisitsEveryInterleaving(t *testing.T) {
+	seen := map[string]bool{}
+	rep, err := model.Explore(model.Config{Strategy: model.Exhaustive}, func(t *model.T) {
+		var events []byte
+		worker := func(id byte) func() {
+			return func() {
+				events = append(events, id)
+				t.Yield()
+				events = append(events, id)
+			}
+		}
+		a := t.Go(worker('a'))
+		b := t.Go(worker('b'))
+		a.Join()
+		b.Join()
+		seen[string(events)] = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.Exhausted {
+		t.Fatalf("exploration not exhausted after %d executions", rep.Executions)
+	}
+	// Two goroutines with two events each interleave in C(4,2) = 6 ways.
+	if len(seen) != 6 {
+		t.Fatalf("distinct interleavings: got %d (%v), want 6", len(seen), seen)
+	}
+}
+
+func TestExplore_PreemptionBoundPrunes(t *testing.T) {
+	body := func(t *model.T) {
+		worker := func() {
+			for i := 0; i < 3; i++ {
+				t.Yield()
+			}
+		}
+		a := t.Go(worker)
+		b := t.Go(worker)
+		a.Join()
+		b.Join()
+	}
+	full, err := model.Explore(model.Config{Strategy: model.Exhaustive, Executions: 1 << 20}, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounded, err := model.Explore(model.Config{Strategy: model.Exhaustive, Executions: 1 << 20, PreemptionBound: 1}, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !full.Exhausted || !bounded.Exhausted {
+		t.Fatalf("exploration not exhausted: full=%+v bounded=%+v", full, bounded)
+	}
+	if bounded.Executions >= full.Executions {
+		t.Fatalf("preemption bound did not prune: bounded=%d full=%d", bounded.Executions, full.Executions)
+	}
+}
+
+func TestExplore_PanicIsFailure(t *testing.T) {
+	_, err := model.Explore(model.Config{Executions: 1}, func(t *model.T) {
+		t.Go(func() { panic("boom") }).Join()
+	})
+	var f *model.Failure
+	if !errors.As(err, &f) || !strings.Contains(f.Message, "boom") {
+		t.Fatalf("expected panic failure, got %v", err)
+	}
+}
+
+func TestExplore_DetectsDeadlock(t *testing.T) {
+	_, err := model.Explore(model.Config{Executions: 1}, func(t *model.T) {
+		var a, b *model.Handle
+		a = t.Go(func() { b.Join() })
+		b = t.Go(func() { a.Join() })
+		a.Join()
+	})
+	var f *model.Failure
+	if !errors.As(err, &f) || !strings.Contains(f.Message, "deadlock") {
+		t.Fatalf("expected deadlock failure, got %v", err)
+	}
+}
+
+func TestExplore_TruncatesAtMaxSteps(t *testing.T) {
+	rep, err := model.Explore(model.Config{Executions: 3, MaxSteps: 10}, func(t *model.T) {
+		for {
+			t.Yield()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Executions != 3 || rep.Truncated != 3 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+}
+
+func TestExplore_CASCounterIsLinearizable(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	rep, err := model.Explore(model.Config{Strategy: model.Exhaustive, PreemptionBound: 2}, func(t *model.T) {
+		p := dwcas.New(0, 0)
+		incr := func() {
+			old := dwcas.Uint128{}
+			for {
+				prev, ok := p.AcqRel(old, dwcas.Uint128{Lo: old.Lo + 1, Hi: old.Hi})
+				if ok {
+					return
+				}
+				old = prev
+			}
+		}
+		a := t.Go(incr)
+		b := t.Go(incr)
+		a.Join()
+		b.Join()
+		got, _ := p.Relaxed(dwcas.Uint128{}, dwcas.Uint128{})
+		t.Assert(got.Lo == 2, "counter=%d, want 2", got.Lo)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.Exhausted {
+		t.Fatalf("exploration not exhausted: %+v", rep)
+	}
+}
+
+func TestExplore_CASBlindStoreLosesUpdate(t *testing.T) {
+	if !model.Instrumented() {
+		t.Skip("requires -tags=dwcas_model")
+	}
+	_, err := model.Explore(model.Config{Strategy: model.Exhaustive}, func(t *model.T) {
+		p := dwcas.New(0, 0)
+		incr := func() {
+			// Broken: the snapshot and the store are separate CAS operations.
+			cur, _ := p.Acquire(dwcas.Uint128{}, dwcas.Uint128{})
+			want := dwcas.Uint128{Lo: cur.Lo + 1}
+			old := cur
+			for {
+				prev, ok := p.Release(old, want)
+				if ok {
+					return
+				}
+				old = prev
+			}
+		}
+		a := t.Go(incr)
+		b := t.Go(incr)
+		a.Join()
+		b.Join()
+		got, _ := p.Relaxed(dwcas.Uint128{}, dwcas.Uint128{})
+		t.Assert(got.Lo == 2, "counter=%d, want 2", got.Lo)
+	})
+	var f *model.Failure
+	if !errors.As(err, &f) {
+		t.Fatalf("expected the lost update to be found, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import "math/rand/v2"
+
+// chooser resolves one nondeterministic choice among n > 1 alternatives.
+type chooser interface {
+	choose(n int) (int, bool)
+}
+
+// random chooses uniformly from a seeded generator.
+type random struct {
+	rng *rand.Rand
+}
+
+func newRandom(seed int64) *random {
+	return &random{rng: rand.New(rand.NewPCG(uint64(seed), 0x9e3779b97f4a7c15))}
+}
+
+func (r *random) choose(n int) (int, bool) {
+	return r.rng.IntN(n), true
+}
+
+// replay follows a recorded schedule and then always picks the first
+// alternative.
+type replay struct {
+	schedule []int
+	pos      int
+}
+
+func (r *replay) choose(n int) (int, bool) {
+	if r.pos >= len(r.schedule) {
+		return 0, true
+	}
+	c := r.schedule[r.pos]
+	r.pos++
+	if c < 0 || c >= n {
+		return 0, false
+	}
+	return c, true
+}
+
+// dfs enumerates every choice sequence depth-first.
+//
+// Each execution replays the recorded prefix and extends it with first
+// alternatives. advance then bumps the deepest choice that still has untried
+// alternatives.
+type dfs struct {
+	stack []branch
+	pos   int
+}
+
+type branch struct {
+	taken int
+	n     int
+}
+
+func (d *dfs) rewind() { d.pos = 0 }
+
+func (d *dfs) choose(n int) (int, bool) {
+	if d.pos < len(d.stack) {
+		b := d.stack[d.pos]
+		d.pos++
+		// A different fan-out at the same position means the body is not
+		// deterministic under a fixed schedule.
+		return b.taken, b.n == n
+	}
+	d.stack = append(d.stack, branch{taken: 0, n: n})
+	d.pos++
+	return 0, true
+}
+
+func (d *dfs) advance() bool {
+	// Choices past the point where the last execution stopped were never
+	// reached; they must not be bumped.
+	d.stack = d.stack[:d.pos]
+	for len(d.stack) > 0 {
+		top := &d.stack[len(d.stack)-1]
+		if top.taken+1 < top.n {
+			top.taken++
+			return true
+		}
+		d.stack = d.stack[:len(d.stack)-1]
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import "fmt"
+
+// T is the handle passed to an [Explore] body. It is shared by every managed
+// goroutine of the execution.
+type T struct {
+	e *execution
+}
+
+// Handle refers to a goroutine started by [T.Go].
+type Handle struct {
+	e  *execution
+	th *thread
+}
+
+// Go starts fn on a new managed goroutine. The goroutine first runs when the
+// scheduler selects it.
+func (t *T) Go(fn func()) *Handle {
+	return &Handle{e: t.e, th: t.e.spawn(fn)}
+}
+
+// Yield is an explicit scheduling point.
+func (t *T) Yield() {
+	t.e.yield()
+}
+
+// Fatalf records a failure and stops the execution.
+func (t *T) Fatalf(format string, args ...any) {
+	t.e.fail(fmt.Sprintf(format, args...))
+	panic(abortSignal{})
+}
+
+// Assert calls [T.Fatalf] if cond is false.
+func (t *T) Assert(cond bool, format string, args ...any) {
+	if !cond {
+		t.Fatalf(format, args...)
+	}
+}
+
+// Join blocks the calling managed goroutine until the goroutine of h returns.
+// Everything the joined goroutine did is visible to the caller afterwards.
+func (h *Handle) Join() {
+	e := h.e
+	cur := e.cur
+	for !h.th.done {
+		cur.joining = h.th
+		e.yield()
+	}
+	cur.joining = nil
+	if e.mem != nil {
+		cur.mem.cur.join(h.th.mem.cur)
+	}
+}
@@ -0,0 +1,121 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// Uint64 is a 64-bit atomic whose operations take an explicit [Order]. It is the
+// companion of [dwcas.Uint128] for modelling the data an algorithm publishes.
+//
+// Every operation is a scheduling point inside [Explore]. Under the Weak memory
+// model a load may observe a stale store unless an acquire operation or fence
+// orders it after the store. Outside [Explore] the operations are
+// sequentially consistent sync/atomic operations.
+//
+// The zero value is ready to use. A Uint64 must not be copied after first use.
+type Uint64 struct {
+	_ noCopy
+	v uint64
+}
+
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+
+// sim yields and returns the execution if x must be simulated.
+func (x *Uint64) sim() (*execution, unsafe.Pointer) {
+	e := current()
+	if e == nil {
+		return nil, nil
+	}
+	e.yield()
+	if e.mem == nil {
+		return nil, nil
+	}
+	return e, unsafe.Pointer(&x.v)
+}
+
+// Load atomically loads x. o must be Relaxed, Acquire or SeqCst.
+func (x *Uint64) Load(o Order) uint64 {
+	e, p := x.sim()
+	if e == nil {
+		return atomic.LoadUint64(&x.v)
+	}
+	if o == SeqCst {
+		e.mem.fence(e.cur.mem, false, false, true)
+	}
+	v, _ := e.load(p, false, o.acquires())
+	return v
+}
+
+// Store atomically stores v into x. o must be Relaxed, Release or SeqCst.
+func (x *Uint64) Store(v uint64, o Order) {
+	e, p := x.sim()
+	if e == nil {
+		atomic.StoreUint64(&x.v, v)
+		return
+	}
+	if o == SeqCst {
+		e.mem.fence(e.cur.mem, false, false, true)
+	}
+	e.store(p, false, v, 0, o.releases())
+	if o == SeqCst {
+		e.mem.fence(e.cur.mem, false, false, true)
+	}
+}
+
+// Swap atomically stores v into x and returns the previous value.
+func (x *Uint64) Swap(v uint64, o Order) (old uint64) {
+	e, p := x.sim()
+	if e == nil {
+		return atomic.SwapUint64(&x.v, v)
+	}
+	return e.rmw64(p, o, func(uint64) uint64 { return v })
+}
+
+// Add atomically adds delta to x and returns the new value.
+func (x *Uint64) Add(delta uint64, o Order) (new uint64) {
+	e, p := x.sim()
+	if e == nil {
+		return atomic.AddUint64(&x.v, delta)
+	}
+	return e.rmw64(p, o, func(cur uint64) uint64 { return cur + delta }) + delta
+}
+
+// CompareAndSwap atomically replaces x with new if it equals old. A failed
+// compare-and-swap is a load with acquire ordering if o has acquire semantics.
+func (x *Uint64) CompareAndSwap(old, new uint64, o Order) (swapped bool) {
+	e, p := x.sim()
+	if e == nil {
+		return atomic.CompareAndSwapUint64(&x.v, old, new)
+	}
+	if o == SeqCst {
+		e.mem.fence(e.cur.mem, false, false, true)
+	}
+	_, _, swapped = e.cas(p, false, old, 0, new, 0, o.acquires(), o.releases(), o.acquires())
+	if o == SeqCst {
+		e.mem.fence(e.cur.mem, false, false, true)
+	}
+	return swapped
+}
+
+// rmw64 is a 64-bit read-modify-write with the ordering o that always writes
+// f(cur) and returns cur.
+func (e *execution) rmw64(p unsafe.Pointer, o Order, f func(cur uint64) uint64) (prev uint64) {
+	if o == SeqCst {
+		e.mem.fence(e.cur.mem, false, false, true)
+	}
+	prev, _, _ = e.rmw(p, false, o.acquires(), o.releases(), func(lo, _ uint64) (uint64, uint64, bool) {
+		return f(lo), 0, true
+	})
+	if o == SeqCst {
+		e.mem.fence(e.cur.mem, false, false, true)
+	}
+	return prev
+}
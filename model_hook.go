@@ -0,0 +1,30 @@
+//go:build dwcas_model
+
+package dwcas
+
+import (
+	"unsafe"
+
+	"code.hybscloud.com/dwcas/internal/hook"
+)
+
+// hookCas128 routes a CAS through the installed instrumentation backend.
+// It reports handled=false when the architecture implementation must run.
+func hookCas128(p *Uint128, old, new Uint128, order hook.Order) (prev Uint128, swapped, handled bool) {
+	b := hook.Current()
+	if b == nil {
+		return Uint128{}, false, false
+	}
+	lo, hi, ok, handled := b.Cas128((*uint64)(unsafe.Pointer(p)), old.Lo, old.Hi, new.Lo, new.Hi, order)
+	return Uint128{Lo: lo, Hi: hi}, ok, handled
+}
+
+// hookBarrier routes a manual barrier through the installed instrumentation
+// backend.
+func hookBarrier(fence hook.Fence) (handled bool) {
+	b := hook.Current()
+	if b == nil {
+		return false
+	}
+	return b.Barrier(fence)
+}
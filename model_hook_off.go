@@ -0,0 +1,13 @@
+//go:build !dwcas_model
+
+package dwcas
+
+import "code.hybscloud.com/dwcas/internal/hook"
+
+// hookCas128 is compiled out in normal builds.
+func hookCas128(_ *Uint128, _, _ Uint128, _ hook.Order) (prev Uint128, swapped, handled bool) {
+	return Uint128{}, false, false
+}
+
+// hookBarrier is compiled out in normal builds.
+func hookBarrier(_ hook.Fence) (handled bool) { return false }
@@ -32,6 +32,32 @@ func New(lo, hi uint64) *Uint128 {
 	return p
 }
 
+// NewSlice returns a heap-allocated slice of n zero Uint128 values whose backing
+// array is 16-byte aligned, so every element is a valid CAS target.
+//
+// Safety notes:
+//   - Do not append to the returned slice: a reallocated backing array is not
+//     guaranteed to be aligned.
+//   - Keep the slice (or a pointer to one of its elements) reachable while
+//     elements are in use.
+//
+// NewSlice panics if n is negative.
+//
+//go:nocheckptr
+func NewSlice(n int) []Uint128 {
+	if n < 0 {
+		panic("dwcas: NewSlice: negative length")
+	}
+	if n == 0 {
+		return []Uint128{}
+	}
+	// As in New, one extra word absorbs the possible +8 misalignment.
+	mem := make([]uint64, 2*n+1)
+	base := uintptr(unsafe.Pointer(unsafe.SliceData(mem)))
+	off := (base & uintptr(15)) >> 3 // 0 or 1 (words)
+	return unsafe.Slice((*Uint128)(unsafe.Pointer(&mem[off])), n)
+}
+
 // CanPlaceAlignedUint128 reports whether p has enough remaining capacity from off
 // to place a 16-byte aligned *Uint128 at or after p[off].
 //
@@ -64,13 +90,79 @@ func PlaceAlignedUint128(p []byte, off int) (n int, u128 *Uint128) {
 		panic("dwcas: PlaceAlignedUint128: insufficient space")
 	}
 
-	base := uintptr(unsafe.Pointer(unsafe.SliceData(p)))
-	start := base + uintptr(off)
+	base := unsafe.Pointer(unsafe.SliceData(p))
+	start := uintptr(base) + uintptr(off)
 
 	aligned := (start + 15) &^ uintptr(15) // round up to 16-byte boundary
 	pad := int(aligned - start)            // 0..15
 	n = pad + 16                           // 16..31
 
-	u128 = (*Uint128)(unsafe.Pointer(aligned))
+	// Derive the result from base rather than from the uintptr: a pointer
+	// rebuilt from an integer hides the data flow from escape analysis, which
+	// could then leave p's backing array on the caller's stack.
+	u128 = (*Uint128)(unsafe.Add(base, off+pad))
 	return n, u128
 }
+
+// noCopy may be embedded into structs which must not be copied after first
+// use; go vet's copylocks check reports copies.
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+
+// inlineCell is a Uint128 cell embedded by value in the atomic wrapper types.
+// It reserves three words and uses whichever two-word window is 16-byte
+// aligned, so the wrappers need no allocation and their zero value is a zero
+// cell.
+type inlineCell struct {
+	_ noCopy
+	v [3]uint64
+}
+
+//go:nocheckptr
+func (c *inlineCell) ptr() *Uint128 {
+	base := unsafe.Pointer(&c.v)
+	off := uintptr(base) & 15 // 0 or 8
+	return (*Uint128)(unsafe.Add(base, off))
+}
+
+// swap stores x with acq_rel ordering and returns the previous value.
+func (c *inlineCell) swap(x Uint128) (old Uint128) {
+	return c.ptr().swap(x)
+}
+
+// update replaces the value v with f(v) as [Uint128.update] does and returns
+// the value stored.
+func (c *inlineCell) update(f func(Uint128) Uint128) Uint128 {
+	return c.ptr().update(f)
+}
+
+// swap stores x into the aligned cell p with acq_rel ordering and returns the
+// previous value. It is the CAS loop behind the Swap methods of the cell
+// types.
+func (p *Uint128) swap(x Uint128) (old Uint128) {
+	for {
+		prev, ok := p.AcqRel(old, x)
+		if ok {
+			return old
+		}
+		old = prev
+	}
+}
+
+// update replaces the value v of the aligned cell p with f(v) in a CAS loop
+// with acq_rel ordering and returns the value stored. The loop compares bit
+// patterns only, so f may compute values, such as NaNs, that do not compare
+// equal to themselves.
+func (p *Uint128) update(f func(Uint128) Uint128) Uint128 {
+	cur := p.Load()
+	for {
+		next := f(cur)
+		prev, ok := p.AcqRel(cur, next)
+		if ok {
+			return next
+		}
+		cur = prev
+	}
+}
@@ -0,0 +1,311 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dwcas
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"code.hybscloud.com/dwcas/internal/race"
+)
+
+// RWSpinLock is a reader-writer lock whose whole state is one [Uint128], so
+// every transition is a single CAS.
+//
+// The low word holds the number of active readers, the number of writers
+// waiting, and flags for a held write lock, a pending upgrade and parked
+// waiters. The high word is a fairness ticket pair (next, serving): RLock and
+// Lock take the next ticket and enter in ticket order, readers with adjacent
+// tickets together. A writer therefore waits only for the readers and writers
+// that arrived before it and is never starved.
+//
+// Waiters spin, then yield, then park until the state changes. Critical
+// sections should still be short; a lock that is held for long is better
+// served by [sync.RWMutex].
+//
+// The zero value is an unlocked lock. A RWSpinLock must not be copied after
+// first use.
+type RWSpinLock struct {
+	state inlineCell
+
+	mu   sync.Mutex    // guards wake
+	wake chan struct{} // closed when a state change may unblock parked waiters
+
+	// raceSync gives the race detector, which does not see the CAS as
+	// synchronization, a happens-before edge for every transition.
+	raceSync atomic.Uint64
+}
+
+// Layout of the low word.
+const (
+	rwReaders    = 1<<32 - 1 // active readers
+	rwWaitingOne = 1 << 32   // one waiting writer; bits 32-60
+	rwParked     = 1 << 61   // some waiter is parked
+	rwUpgrade    = 1 << 62   // a reader is upgrading
+	rwWriter     = 1 << 63   // the write lock is held
+)
+
+// Spin budget: busy retries, then retries that yield, then parking.
+const (
+	rwSpins  = 32
+	rwYields = 128
+)
+
+func rwNext(s Uint128) uint32    { return uint32(s.Hi) }
+func rwServing(s Uint128) uint32 { return uint32(s.Hi >> 32) }
+
+// rwTake returns s with the next ticket taken.
+func rwTake(s Uint128) Uint128 {
+	s.Hi = s.Hi&^(1<<32-1) | uint64(rwNext(s)+1)
+	return s
+}
+
+// rwServe returns s with the serving ticket advanced.
+func rwServe(s Uint128) Uint128 {
+	s.Hi += 1 << 32
+	return s
+}
+
+// RLock locks l for reading.
+func (l *RWSpinLock) RLock() {
+	p := l.state.ptr()
+	old := p.Load()
+	for {
+		t := rwNext(old)
+		next := rwTake(old)
+		entered := rwServing(old) == t && old.Lo&(rwWriter|rwUpgrade) == 0
+		if entered {
+			next = rwServe(next)
+			next.Lo++
+		}
+		if l.cas(p, old, next) {
+			if entered {
+				return
+			}
+			l.wait(func(s Uint128) (Uint128, bool) {
+				if rwServing(s) != t || s.Lo&(rwWriter|rwUpgrade) != 0 {
+					return s, false
+				}
+				s = rwServe(s)
+				s.Lo++
+				return s, true
+			})
+			return
+		}
+		old = p.Load()
+	}
+}
+
+// TryRLock locks l for reading if that is possible without waiting, and
+// reports whether it did. It fails while a writer holds or waits for the lock.
+func (l *RWSpinLock) TryRLock() bool {
+	p := l.state.ptr()
+	for {
+		old := p.Load()
+		if rwNext(old) != rwServing(old) || old.Lo&(rwWriter|rwUpgrade) != 0 {
+			return false
+		}
+		next := rwServe(rwTake(old))
+		next.Lo++
+		if l.cas(p, old, next) {
+			return true
+		}
+	}
+}
+
+// RUnlock undoes a single RLock call. It panics if l is not locked for
+// reading.
+func (l *RWSpinLock) RUnlock() {
+	p := l.state.ptr()
+	for {
+		old := p.Load()
+		if old.Lo&rwReaders == 0 {
+			panic("dwcas: RWSpinLock: RUnlock of unlocked lock")
+		}
+		next := old
+		next.Lo--
+		if l.cas(p, old, next) {
+			return
+		}
+	}
+}
+
+// Lock locks l for writing.
+func (l *RWSpinLock) Lock() {
+	p := l.state.ptr()
+	old := p.Load()
+	for {
+		t := rwNext(old)
+		next := rwTake(old)
+		entered := rwServing(old) == t && old.Lo&(rwReaders|rwWriter|rwUpgrade) == 0
+		if entered {
+			next = rwServe(next)
+			next.Lo |= rwWriter
+		} else {
+			next.Lo += rwWaitingOne
+		}
+		if l.cas(p, old, next) {
+			if entered {
+				return
+			}
+			l.wait(func(s Uint128) (Uint128, bool) {
+				if rwServing(s) != t || s.Lo&(rwReaders|rwWriter|rwUpgrade) != 0 {
+					return s, false
+				}
+				s = rwServe(s)
+				s.Lo = (s.Lo - rwWaitingOne) | rwWriter
+				return s, true
+			})
+			return
+		}
+		old = p.Load()
+	}
+}
+
+// TryLock locks l for writing if that is possible without waiting, and reports
+// whether it did.
+func (l *RWSpinLock) TryLock() bool {
+	p := l.state.ptr()
+	for {
+		old := p.Load()
+		if rwNext(old) != rwServing(old) || old.Lo&(rwReaders|rwWriter|rwUpgrade) != 0 {
+			return false
+		}
+		next := rwServe(rwTake(old))
+		next.Lo |= rwWriter
+		if l.cas(p, old, next) {
+			return true
+		}
+	}
+}
+
+// Unlock unlocks l for writing. It panics if l is not locked for writing.
+func (l *RWSpinLock) Unlock() {
+	p := l.state.ptr()
+	for {
+		old := p.Load()
+		if old.Lo&rwWriter == 0 {
+			panic("dwcas: RWSpinLock: Unlock of unlocked lock")
+		}
+		next := old
+		next.Lo &^= rwWriter
+		if l.cas(p, old, next) {
+			return
+		}
+	}
+}
+
+// Upgrade turns the caller's read lock into the write lock, waiting for the
+// other readers to leave; readers that have not yet entered wait behind it.
+// Only one upgrade can be pending: if another reader is already upgrading,
+// Upgrade returns false at once and the caller still holds its read lock,
+// which it must release for the other upgrade to finish.
+func (l *RWSpinLock) Upgrade() bool {
+	p := l.state.ptr()
+	for {
+		old := p.Load()
+		if old.Lo&rwReaders == 0 {
+			panic("dwcas: RWSpinLock: Upgrade of unlocked lock")
+		}
+		if old.Lo&rwUpgrade != 0 {
+			return false
+		}
+		next := old
+		next.Lo |= rwUpgrade
+		if l.cas(p, old, next) {
+			break
+		}
+	}
+	l.wait(func(s Uint128) (Uint128, bool) {
+		if s.Lo&rwReaders != 1 {
+			return s, false
+		}
+		s.Lo = (s.Lo-1)&^rwUpgrade | rwWriter
+		return s, true
+	})
+	return true
+}
+
+// Downgrade turns the write lock into a read lock without letting another
+// writer in between. It panics if l is not locked for writing.
+func (l *RWSpinLock) Downgrade() {
+	p := l.state.ptr()
+	for {
+		old := p.Load()
+		if old.Lo&rwWriter == 0 {
+			panic("dwcas: RWSpinLock: Downgrade of unlocked lock")
+		}
+		next := old
+		next.Lo = next.Lo&^rwWriter + 1
+		if l.cas(p, old, next) {
+			return
+		}
+	}
+}
+
+// cas replaces the state old with next, clearing the parked flag, and wakes
+// parked waiters if it was set.
+func (l *RWSpinLock) cas(p *Uint128, old, next Uint128) bool {
+	next.Lo &^= rwParked
+	if race.Enabled {
+		l.raceSync.Add(1)
+	}
+	if _, ok := p.AcqRel(old, next); !ok {
+		return false
+	}
+	if race.Enabled {
+		l.raceSync.Load()
+	}
+	if old.Lo&rwParked != 0 {
+		l.mu.Lock()
+		if l.wake != nil {
+			close(l.wake)
+			l.wake = nil
+		}
+		l.mu.Unlock()
+	}
+	return true
+}
+
+// wait retries step on the current state until it reports that the caller may
+// proceed and the returned state is installed.
+func (l *RWSpinLock) wait(step func(Uint128) (Uint128, bool)) {
+	p := l.state.ptr()
+	for i := 0; ; i++ {
+		s := p.Load()
+		if next, ok := step(s); ok {
+			if l.cas(p, s, next) {
+				return
+			}
+			continue
+		}
+		switch {
+		case i < rwSpins:
+		case i < rwYields:
+			runtime.Gosched()
+		default:
+			l.park(p, s)
+		}
+	}
+}
+
+// park blocks until the next state change after s. It returns at once if the
+// state is no longer s.
+func (l *RWSpinLock) park(p *Uint128, s Uint128) {
+	l.mu.Lock()
+	// Setting the flag while holding mu means the waker, which clears it
+	// before taking mu, closes the channel this waiter is about to wait on.
+	if _, ok := p.AcqRel(s, Uint128{Lo: s.Lo | rwParked, Hi: s.Hi}); !ok {
+		l.mu.Unlock()
+		return
+	}
+	if l.wake == nil {
+		l.wake = make(chan struct{})
+	}
+	ch := l.wake
+	l.mu.Unlock()
+	<-ch
+}
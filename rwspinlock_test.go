@@ -0,0 +1,223 @@
+package dwcas_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/dwcas"
+)
+
+func TestRWSpinLock_TryLock(t *testing.T) {
+	var l dwcas.RWSpinLock
+	if !l.TryRLock() || !l.TryRLock() {
+		t.Fatalf("TryRLock failed on a read-locked lock")
+	}
+	if l.TryLock() {
+		t.Fatalf("TryLock succeeded while readers hold the lock")
+	}
+	l.RUnlock()
+	l.RUnlock()
+	if !l.TryLock() {
+		t.Fatalf("TryLock failed on an unlocked lock")
+	}
+	if l.TryLock() || l.TryRLock() {
+		t.Fatalf("lock acquired while a writer holds it")
+	}
+	l.Unlock()
+	l.Lock()
+	l.Unlock()
+	l.RLock()
+	l.RUnlock()
+}
+
+func TestRWSpinLock_Panics(t *testing.T) {
+	for name, f := range map[string]func(l *dwcas.RWSpinLock){
+		"Unlock":    func(l *dwcas.RWSpinLock) { l.Unlock() },
+		"RUnlock":   func(l *dwcas.RWSpinLock) { l.RUnlock() },
+		"Upgrade":   func(l *dwcas.RWSpinLock) { l.Upgrade() },
+		"Downgrade": func(l *dwcas.RWSpinLock) { l.Downgrade() },
+		"UnlockRead": func(l *dwcas.RWSpinLock) {
+			l.RLock()
+			l.Unlock()
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			f(new(dwcas.RWSpinLock))
+		}()
+	}
+}
+
+func TestRWSpinLock_UpgradeDowngrade(t *testing.T) {
+	var l dwcas.RWSpinLock
+	l.RLock()
+	l.RLock()
+	upgraded := make(chan bool)
+	go func() { upgraded <- l.Upgrade() }()
+	select {
+	case <-upgraded:
+		t.Fatalf("Upgrade finished while another reader holds the lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if l.Upgrade() {
+		t.Fatalf("second concurrent Upgrade succeeded")
+	}
+	if l.TryRLock() {
+		t.Fatalf("new reader entered during a pending upgrade")
+	}
+	l.RUnlock()
+	if !<-upgraded {
+		t.Fatalf("Upgrade failed")
+	}
+	if l.TryRLock() {
+		t.Fatalf("reader entered an upgraded lock")
+	}
+
+	writer := make(chan struct{})
+	go func() {
+		l.Lock()
+		close(writer)
+		l.Unlock()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	l.Downgrade()
+	l.RUnlock()
+	<-writer
+	if !l.TryLock() {
+		t.Fatalf("lock not released after Downgrade and RUnlock")
+	}
+}
+
+func TestRWSpinLock_ReadersAndWritersExclude(t *testing.T) {
+	const (
+		workers = 8
+	)
+	iters := 20000
+	if testing.Short() {
+		iters = 2000
+	}
+	var l dwcas.RWSpinLock
+	var a, b int // equal whenever no writer holds the lock
+	var readers, writers atomic.Int32
+	var wg sync.WaitGroup
+	errs := make(chan string, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iters; i++ {
+				switch {
+				case (i+w)%4 == 0:
+					l.Lock()
+					if writers.Add(1) != 1 || readers.Load() != 0 {
+						errs <- "writer overlapped another holder"
+					}
+					a++
+					b++
+					writers.Add(-1)
+					l.Unlock()
+				case (i+w)%16 == 1:
+					l.RLock()
+					if l.Upgrade() {
+						a++
+						b++
+						l.Downgrade()
+					}
+					l.RUnlock()
+				default:
+					l.RLock()
+					readers.Add(1)
+					if writers.Load() != 0 || a != b {
+						errs <- "reader overlapped a writer"
+					}
+					readers.Add(-1)
+					l.RUnlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Fatal(e)
+	}
+}
+
+func TestRWSpinLock_WriterNotStarved(t *testing.T) {
+	var l dwcas.RWSpinLock
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// Readers overlap, so the read lock is never free
+				// without the fairness ticket.
+				l.RLock()
+				time.Sleep(100 * time.Microsecond)
+				l.RUnlock()
+			}
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			l.Lock()
+			l.Unlock()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("writer starved by continuous readers")
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestRWSpinLock_ParkedWaitersWake(t *testing.T) {
+	var l dwcas.RWSpinLock
+	l.Lock()
+	const waiters = 6
+	var wg sync.WaitGroup
+	var entered atomic.Int32
+	for w := 0; w < waiters; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w%2 == 0 {
+				l.Lock()
+				entered.Add(1)
+				l.Unlock()
+			} else {
+				l.RLock()
+				entered.Add(1)
+				l.RUnlock()
+			}
+		}()
+	}
+	// Long enough for every waiter to exhaust its spin budget and park.
+	time.Sleep(50 * time.Millisecond)
+	if entered.Load() != 0 {
+		t.Fatalf("waiter entered a write-locked lock")
+	}
+	l.Unlock()
+	wg.Wait()
+	if entered.Load() != waiters {
+		t.Fatalf("%d of %d waiters entered", entered.Load(), waiters)
+	}
+}
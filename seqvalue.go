@@ -0,0 +1,188 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dwcas
+
+import (
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// SeqValue holds a value of type T, which may be larger than 16 bytes, behind
+// a sequence lock.
+//
+// The lock word is a [Uint128] holding (sequence, writer id). The sequence is
+// even while the value is stable and odd while a writer holds the lock; the
+// writer id identifies that writer, so a release by anyone else is detected.
+// Writers acquire the lock with a CAS and are mutually exclusive. Readers take
+// no lock: a read copies the value between two loads of the lock word and
+// succeeds if the sequence was even and unchanged.
+//
+// The value is kept in words accessed with sync/atomic, so concurrent reads and
+// writes are not data races. T must therefore not contain pointers (including
+// strings, slices, maps, channels, functions and interfaces); [NewSeqValue]
+// panics otherwise.
+//
+// The zero value is not usable; call [NewSeqValue]. A SeqValue must not be
+// copied after first use.
+type SeqValue[T any] struct {
+	lock  inlineCell
+	words []atomic.Uint64
+}
+
+// seqWriters allocates writer ids; 0 means no writer.
+var seqWriters atomic.Uint64
+
+// NewSeqValue returns a SeqValue holding v.
+func NewSeqValue[T any](v T) *SeqValue[T] {
+	if hasPointers(reflect.TypeFor[T]()) {
+		panic("dwcas: NewSeqValue: T must not contain pointers")
+	}
+	size := unsafe.Sizeof(v)
+	s := &SeqValue[T]{words: make([]atomic.Uint64, (size+7)/8)}
+	s.write(&v)
+	return s
+}
+
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array:
+		return t.Len() > 0 && hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	case reflect.Pointer, reflect.UnsafePointer, reflect.String, reflect.Slice,
+		reflect.Map, reflect.Chan, reflect.Func, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// TryLoad makes one attempt to read the value. ok is false if a writer held the
+// lock or completed an update during the attempt. TryLoad is wait-free.
+func (s *SeqValue[T]) TryLoad() (v T, ok bool) {
+	p := s.lock.ptr()
+	before := p.Load()
+	if before.Lo&1 != 0 {
+		return v, false
+	}
+	s.read(&v)
+	after := p.Load()
+	if after.Lo != before.Lo {
+		var zero T
+		return zero, false
+	}
+	return v, true
+}
+
+// Load returns a consistent snapshot of the value, retrying while writers
+// interfere.
+func (s *SeqValue[T]) Load() T {
+	for i := 0; ; i++ {
+		if v, ok := s.TryLoad(); ok {
+			return v
+		}
+		if i >= 64 {
+			runtime.Gosched()
+		}
+	}
+}
+
+// Store sets the value to v.
+func (s *SeqValue[T]) Store(v T) {
+	held := s.acquire()
+	s.write(&v)
+	s.release(held)
+}
+
+// Update replaces the value with f applied to it, holding the write lock while
+// f runs, and returns the stored value. f must not call methods on s. If f
+// panics, the value is left unchanged and the lock is released.
+func (s *SeqValue[T]) Update(f func(T) T) T {
+	held := s.acquire()
+	defer s.release(held)
+	var v T
+	s.read(&v)
+	v = f(v)
+	s.write(&v)
+	return v
+}
+
+// acquire takes the write lock and returns the lock word as held.
+func (s *SeqValue[T]) acquire() Uint128 {
+	p := s.lock.ptr()
+	id := seqWriters.Add(1)
+	cur := Uint128{}
+	for i := 0; ; i++ {
+		if cur.Lo&1 == 0 {
+			held := Uint128{Lo: cur.Lo + 1, Hi: id}
+			prev, ok := p.AcqRel(cur, held)
+			if ok {
+				return held
+			}
+			cur = prev
+			continue
+		}
+		if i >= 64 {
+			runtime.Gosched()
+		}
+		cur = p.Load()
+	}
+}
+
+// release publishes the update and drops the write lock.
+func (s *SeqValue[T]) release(held Uint128) {
+	if _, ok := s.lock.ptr().Release(held, Uint128{Lo: held.Lo + 1}); !ok {
+		panic("dwcas: SeqValue: lock word changed while held")
+	}
+}
+
+// read copies the words into *v. Whole words of an 8-byte aligned T are
+// stored directly; otherwise, and for a trailing partial word, bytes are
+// stored one at a time so that nothing past *v is written.
+func (s *SeqValue[T]) read(v *T) {
+	size := unsafe.Sizeof(*v)
+	base := unsafe.Pointer(v)
+	full := uintptr(0)
+	if unsafe.Alignof(*v) >= 8 {
+		full = size / 8
+	}
+	for i := uintptr(0); i < full; i++ {
+		*(*uint64)(unsafe.Add(base, i*8)) = s.words[i].Load()
+	}
+	var w uint64
+	for off := full * 8; off < size; off++ {
+		if off%8 == 0 {
+			w = s.words[off/8].Load()
+		}
+		*(*byte)(unsafe.Add(base, off)) = byte(w >> (8 * (off % 8)))
+	}
+}
+
+// write copies *v into the words; it is the inverse of read.
+func (s *SeqValue[T]) write(v *T) {
+	size := unsafe.Sizeof(*v)
+	base := unsafe.Pointer(v)
+	full := uintptr(0)
+	if unsafe.Alignof(*v) >= 8 {
+		full = size / 8
+	}
+	for i := uintptr(0); i < full; i++ {
+		s.words[i].Store(*(*uint64)(unsafe.Add(base, i*8)))
+	}
+	var w uint64
+	for off := full * 8; off < size; off++ {
+		w |= uint64(*(*byte)(unsafe.Add(base, off))) << (8 * (off % 8))
+		if off%8 == 7 || off == size-1 {
+			s.words[off/8].Store(w)
+			w = 0
+		}
+	}
+}
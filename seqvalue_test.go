@@ -0,0 +1,167 @@
+package dwcas_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// route is a 48-byte entry whose fields are all derived from one sequence
+// number, so a snapshot mixing two writes is detected.
+type route struct {
+	Prefix dwcas.Uint128
+	Next   [2]uint64
+	Metric uint32
+	Flags  uint32
+	Seq    uint64
+}
+
+func makeRoute(seq uint64) route {
+	return route{
+		Prefix: dwcas.Uint128{Lo: seq, Hi: ^seq},
+		Next:   [2]uint64{seq * 3, seq * 5},
+		Metric: uint32(seq),
+		Flags:  uint32(seq >> 32),
+		Seq:    seq,
+	}
+}
+
+func TestSeqValue_Basic(t *testing.T) {
+	s := dwcas.NewSeqValue(makeRoute(1))
+	if got := s.Load(); got != makeRoute(1) {
+		t.Fatalf("Load: got %+v", got)
+	}
+	s.Store(makeRoute(2))
+	if got, ok := s.TryLoad(); !ok || got != makeRoute(2) {
+		t.Fatalf("TryLoad: got (%+v, %v)", got, ok)
+	}
+	got := s.Update(func(r route) route { return makeRoute(r.Seq + 1) })
+	if got != makeRoute(3) || s.Load() != makeRoute(3) {
+		t.Fatalf("Update: got %+v", got)
+	}
+
+	// Sizes that are not a multiple of 8 and byte alignment.
+	b := dwcas.NewSeqValue([13]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13})
+	b.Update(func(v [13]byte) [13]byte { v[12]++; v[0]--; return v })
+	if got := b.Load(); got != [13]byte{0, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 14} {
+		t.Fatalf("[13]byte: got %v", got)
+	}
+	type odd struct {
+		A uint64
+		B uint16
+	}
+	o := dwcas.NewSeqValue(odd{A: 1, B: 2})
+	o.Store(odd{A: 3, B: 0xffff})
+	if got := o.Load(); got != (odd{A: 3, B: 0xffff}) {
+		t.Fatalf("odd: got %+v", got)
+	}
+}
+
+func TestSeqValue_UpdatePanics(t *testing.T) {
+	s := dwcas.NewSeqValue(makeRoute(1))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("panic in f was not propagated")
+			}
+		}()
+		s.Update(func(route) route { panic("f failed") })
+	}()
+	// The lock was released and the value left as it was.
+	if got, ok := s.TryLoad(); !ok || got != makeRoute(1) {
+		t.Fatalf("TryLoad after panic: got (%+v, %v)", got, ok)
+	}
+	s.Store(makeRoute(2))
+	if got := s.Load(); got != makeRoute(2) {
+		t.Fatalf("Load after Store: got %+v", got)
+	}
+}
+
+func TestNewSeqValue_RejectsPointers(t *testing.T) {
+	for name, f := range map[string]func(){
+		"pointer":   func() { dwcas.NewSeqValue[*int](nil) },
+		"string":    func() { dwcas.NewSeqValue("") },
+		"nested":    func() { dwcas.NewSeqValue(struct{ A [2][]byte }{}) },
+		"interface": func() { dwcas.NewSeqValue[any](nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestSeqValue_NoTornReads(t *testing.T) {
+	const writers, readers = 4, 4
+	iters := 20000
+	if testing.Short() {
+		iters = 2000
+	}
+	s := dwcas.NewSeqValue(makeRoute(0))
+	var stop atomic.Bool
+	var torn, snapshots atomic.Int64
+	var rw sync.WaitGroup
+	rw.Add(readers)
+	for r := 0; r < readers; r++ {
+		go func() {
+			defer rw.Done()
+			for !stop.Load() {
+				v := s.Load()
+				if v != makeRoute(v.Seq) {
+					torn.Add(1)
+				}
+				snapshots.Add(1)
+			}
+		}()
+	}
+	var ww sync.WaitGroup
+	ww.Add(writers)
+	for w := 0; w < writers; w++ {
+		go func() {
+			defer ww.Done()
+			for i := 0; i < iters; i++ {
+				if i%2 == 0 {
+					s.Update(func(r route) route { return makeRoute(r.Seq + 1) })
+				} else {
+					s.Store(makeRoute(uint64(w)<<40 | uint64(i)))
+				}
+			}
+		}()
+	}
+	ww.Wait()
+	stop.Store(true)
+	rw.Wait()
+	if n := torn.Load(); n != 0 {
+		t.Fatalf("%d of %d snapshots were torn", n, snapshots.Load())
+	}
+}
+
+func TestSeqValue_UpdateIsExclusive(t *testing.T) {
+	const workers = 8
+	s := dwcas.NewSeqValue([6]uint64{})
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				s.Update(func(v [6]uint64) [6]uint64 {
+					for j := range v {
+						v[j]++
+					}
+					return v
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if got := s.Load(); got != [6]uint64{8000, 8000, 8000, 8000, 8000, 8000} {
+		t.Fatalf("lost updates: %v", got)
+	}
+}
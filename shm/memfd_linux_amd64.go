@@ -0,0 +1,8 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+// sysMemfdCreate is the memfd_create system call number.
+const sysMemfdCreate = 319
@@ -0,0 +1,395 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package ring provides a bounded lock-free multi-producer multi-consumer
+// queue of byte messages in a shared-memory region (package shm), for
+// processes on one host.
+//
+// The ring is an array of slots, each a control cell followed by data cells
+// holding up to MaxMessage bytes. A control cell is a [dwcas.Uint128]
+// (sequence, state): a slot for position pos is free for a producer while its
+// sequence is pos, holds a message for a consumer while it is pos+1, and is
+// free again for position pos+slots once consumed. Producers and consumers
+// claim a slot with one 128-bit CAS that checks the sequence and records their
+// process ID, then commit with a second CAS. Shared tail and head cursors only
+// say where to look; whoever finds a cursor behind a claimed slot advances it.
+//
+// A process that dies between claim and commit leaves a half-written or
+// half-read slot. When a peer finds such a slot in its way and its owner no
+// longer exists, it recovers the slot with a CAS: a half-written message is
+// dropped, and a half-read slot is freed, losing that message. Delivery is
+// therefore exactly-once among live processes and at-most-once for a message
+// whose producer or consumer crashed. Liveness is checked by process ID and
+// start time, as for [shm.RobustMutex], so all processes using a ring must
+// share a PID namespace.
+package ring
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/internal/proc"
+	"code.hybscloud.com/dwcas/shm"
+)
+
+// Cells of the region before the first slot.
+const (
+	cellConfig = iota // {Lo: slots | cellsPerSlot<<32, Hi: ringMagic}, written last
+	cellTail          // {Lo: next position to produce}
+	cellHead          // {Lo: next position to consume}
+	cellStats         // {Lo: recovered slots}
+	cellSlots
+)
+
+// ringMagic is "DWCSRNG1" read as a little-endian uint64.
+const ringMagic = 0x31474e5253435744
+
+// Slot states, in the top two bits of a control cell's Hi. While writing or
+// reading, the low 32 bits hold the owner's process ID and the next 30 bits
+// the low bits of its start time; while full, the low 32 bits hold the message
+// length.
+const (
+	stateFree    = 0
+	stateWriting = 1 << 62
+	stateFull    = 2 << 62
+	stateReading = 3 << 62
+	stateMask    = 3 << 62
+)
+
+// startMask keeps the low bits of a start time that fit in a control cell.
+// At 100 clock ticks per second they wrap after about 124 days, so a reused
+// PID is taken for the owner only if its process started a multiple of that
+// apart.
+const startMask = 1<<30 - 1
+
+// maxMaxMessage is the largest maxMessage, limited by the 32-bit length field.
+const maxMaxMessage = 1 << 31
+
+var (
+	// ErrNotRing is returned by Open and OpenFile for a region that does not
+	// hold a ring.
+	ErrNotRing = errors.New("ring: region does not hold a ring")
+
+	// testHookClaimed, if set, runs after a producer or consumer claims a
+	// slot and before it commits.
+	testHookClaimed func()
+)
+
+// Ring is a mapped message ring.
+type Ring struct {
+	region       *shm.Region
+	slots        uint64
+	cellsPerSlot int
+	maxMessage   int
+}
+
+// Create creates a ring in a new named shared-memory region with the given
+// number of slots, each holding messages of up to maxMessage bytes.
+//
+// Create panics if slots is not positive or maxMessage is not in
+// [1, 1<<31].
+func Create(name string, slots, maxMessage int) (*Ring, error) {
+	cps := checkSize("Create", slots, maxMessage)
+	region, err := shm.Create(name, cellSlots+slots*cps)
+	if err != nil {
+		return nil, err
+	}
+	return initialize(region, slots, cps), nil
+}
+
+// CreateAnonymous is like [Create] but uses an anonymous region. Other
+// processes open the ring by inheriting or receiving the returned file and
+// calling [OpenFile].
+func CreateAnonymous(slots, maxMessage int) (*Ring, *os.File, error) {
+	cps := checkSize("CreateAnonymous", slots, maxMessage)
+	region, f, err := shm.CreateAnonymous("dwcas-ring", cellSlots+slots*cps)
+	if err != nil {
+		return nil, nil, err
+	}
+	return initialize(region, slots, cps), f, nil
+}
+
+// Open opens the ring in the named region created by [Create].
+func Open(name string) (*Ring, error) {
+	region, err := shm.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return attach(region)
+}
+
+// OpenFile opens the ring in the region in f.
+func OpenFile(f *os.File) (*Ring, error) {
+	region, err := shm.OpenFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return attach(region)
+}
+
+// checkSize validates the ring geometry and returns the cells per slot.
+func checkSize(fn string, slots, maxMessage int) int {
+	if slots <= 0 {
+		panic("ring: " + fn + ": slots must be positive")
+	}
+	if maxMessage <= 0 || int64(maxMessage) > maxMaxMessage {
+		panic("ring: " + fn + ": maxMessage out of range")
+	}
+	return 1 + int((int64(maxMessage)+15)/16)
+}
+
+func initialize(region *shm.Region, slots, cps int) *Ring {
+	for i := 0; i < slots; i++ {
+		region.Cell(cellSlots+i*cps).AcqRel(dwcas.Uint128{}, dwcas.Uint128{Lo: uint64(i)})
+	}
+	region.Cell(cellConfig).AcqRel(dwcas.Uint128{}, dwcas.Uint128{Lo: uint64(slots) | uint64(cps)<<32, Hi: ringMagic})
+	return &Ring{region: region, slots: uint64(slots), cellsPerSlot: cps, maxMessage: (cps - 1) * 16}
+}
+
+func attach(region *shm.Region) (*Ring, error) {
+	if region.Cells() < cellSlots {
+		region.Close()
+		return nil, ErrNotRing
+	}
+	cfg := region.Cell(cellConfig).Load()
+	if cfg == (dwcas.Uint128{}) {
+		region.Close()
+		return nil, shm.ErrNotReady
+	}
+	slots, cps := int(uint32(cfg.Lo)), int(cfg.Lo>>32)
+	if cfg.Hi != ringMagic || slots == 0 || cps < 2 || region.Cells() < cellSlots+slots*cps {
+		region.Close()
+		return nil, ErrNotRing
+	}
+	return &Ring{region: region, slots: uint64(slots), cellsPerSlot: cps, maxMessage: (cps - 1) * 16}, nil
+}
+
+// Slots returns the capacity of r in messages.
+func (r *Ring) Slots() int {
+	return int(r.slots)
+}
+
+// MaxMessage returns the largest message r holds, in bytes. It is maxMessage
+// as passed to Create rounded up to a multiple of 16.
+func (r *Ring) MaxMessage() int {
+	return r.maxMessage
+}
+
+// Recovered returns the number of slots that processes sharing r have
+// recovered from crashed producers or consumers.
+func (r *Ring) Recovered() uint64 {
+	return r.region.Cell(cellStats).Load().Lo
+}
+
+// Close unmaps r. Producers and consumers of r must not be used afterwards.
+func (r *Ring) Close() error {
+	return r.region.Close()
+}
+
+// Producer returns a producer for r. Producers are safe for concurrent use.
+func (r *Ring) Producer() *Producer {
+	return &Producer{r: r, owner: ownerWord(stateWriting)}
+}
+
+// Consumer returns a consumer for r. Consumers are safe for concurrent use.
+func (r *Ring) Consumer() *Consumer {
+	return &Consumer{r: r, owner: ownerWord(stateReading)}
+}
+
+// ownerWord returns the control Hi of a slot this process holds in state.
+func ownerWord(state uint64) uint64 {
+	self := proc.Self()
+	return state | (self.Hi&startMask)<<32 | uint64(uint32(self.Lo))
+}
+
+// alive reports whether the process recorded in the control Hi owner still
+// runs.
+func alive(owner uint64) bool {
+	start, ok := proc.StartTime(int(uint32(owner)))
+	return ok && start&startMask == owner>>32&startMask
+}
+
+// Producer sends messages to a ring.
+type Producer struct {
+	r     *Ring
+	owner uint64 // control Hi while this process writes a slot
+}
+
+// TrySend copies msg into the ring and reports whether there was room.
+//
+// TrySend panics if msg is longer than [Ring.MaxMessage].
+func (p *Producer) TrySend(msg []byte) bool {
+	r := p.r
+	if len(msg) > r.maxMessage {
+		panic("ring: TrySend: message too large")
+	}
+	tail := r.region.Cell(cellTail)
+	for {
+		pos := tail.Load().Lo
+		ctl := r.control(pos)
+		c := ctl.Load()
+		switch d := int64(c.Lo - pos); {
+		case d == 0 && c.Hi&stateMask == stateFree:
+			claimed := dwcas.Uint128{Lo: pos, Hi: p.owner}
+			if _, ok := ctl.AcqRel(c, claimed); !ok {
+				continue
+			}
+			advance(tail, pos)
+			if testHookClaimed != nil {
+				testHookClaimed()
+			}
+			r.write(pos, msg)
+			commit := dwcas.Uint128{Lo: pos + 1, Hi: stateFull | uint64(len(msg))}
+			if _, ok := ctl.Release(claimed, commit); !ok {
+				panic("ring: slot recovered while its producer was alive")
+			}
+			return true
+		case d >= 0:
+			// Another producer claimed pos; move the tail past it.
+			advance(tail, pos)
+		case c.Hi&stateMask == stateReading && !alive(c.Hi):
+			// The consumer of the previous lap died reading this slot.
+			if _, ok := ctl.AcqRel(c, dwcas.Uint128{Lo: pos, Hi: stateFree}); ok {
+				r.recovered()
+			}
+		default:
+			return false
+		}
+	}
+}
+
+// Send copies msg into the ring, waiting for room. Waiting spins, then
+// yields, then sleeps briefly between attempts, since other processes cannot
+// wake it.
+//
+// Send panics if msg is longer than [Ring.MaxMessage].
+func (p *Producer) Send(msg []byte) {
+	for i := 0; !p.TrySend(msg); i++ {
+		backoff(i)
+	}
+}
+
+// Consumer receives messages from a ring.
+type Consumer struct {
+	r     *Ring
+	owner uint64 // control Hi while this process reads a slot
+}
+
+// TryReceive appends the oldest message to buf and returns the result. ok is
+// false if the ring held no committed message.
+func (c *Consumer) TryReceive(buf []byte) (msg []byte, ok bool) {
+	r := c.r
+	head := r.region.Cell(cellHead)
+	for {
+		pos := head.Load().Lo
+		ctl := r.control(pos)
+		s := ctl.Load()
+		switch d := int64(s.Lo - (pos + 1)); {
+		case d == 0 && s.Hi&stateMask == stateFull:
+			claimed := dwcas.Uint128{Lo: pos + 1, Hi: c.owner}
+			if _, ok := ctl.AcqRel(s, claimed); !ok {
+				continue
+			}
+			advance(head, pos)
+			if testHookClaimed != nil {
+				testHookClaimed()
+			}
+			buf = r.read(pos, int(uint32(s.Hi)), buf)
+			if _, ok := ctl.Release(claimed, dwcas.Uint128{Lo: pos + r.slots, Hi: stateFree}); !ok {
+				panic("ring: slot recovered while its consumer was alive")
+			}
+			return buf, true
+		case d >= 0:
+			// Another consumer claimed pos; move the head past it.
+			advance(head, pos)
+		case s.Lo == pos && s.Hi&stateMask == stateWriting && !alive(s.Hi):
+			// The producer died writing this slot: drop the message.
+			if _, ok := ctl.AcqRel(s, dwcas.Uint128{Lo: pos + r.slots, Hi: stateFree}); ok {
+				r.recovered()
+				advance(head, pos)
+			}
+		default:
+			return buf, false
+		}
+	}
+}
+
+// Receive appends the oldest message to buf and returns the result, waiting
+// for one to arrive as [Producer.Send] waits for room.
+func (c *Consumer) Receive(buf []byte) []byte {
+	for i := 0; ; i++ {
+		if msg, ok := c.TryReceive(buf); ok {
+			return msg
+		}
+		backoff(i)
+	}
+}
+
+// control returns the control cell of the slot for pos.
+func (r *Ring) control(pos uint64) *dwcas.Uint128 {
+	return r.region.Cell(cellSlots + int(pos%r.slots)*r.cellsPerSlot)
+}
+
+// word returns the i-th 8-byte data word of the slot for pos.
+func (r *Ring) word(pos uint64, i int) *uint64 {
+	cell := r.region.Cell(cellSlots + int(pos%r.slots)*r.cellsPerSlot + 1 + i/2)
+	if i%2 == 0 {
+		return &cell.Lo
+	}
+	return &cell.Hi
+}
+
+// write stores msg into the data words of the slot for pos. The words are
+// accessed with sync/atomic so that they never race in the Go memory model;
+// the control cell CAS orders them.
+func (r *Ring) write(pos uint64, msg []byte) {
+	for i := 0; i*8 < len(msg); i++ {
+		var w uint64
+		for j, b := range msg[i*8 : min(i*8+8, len(msg))] {
+			w |= uint64(b) << (8 * j)
+		}
+		atomic.StoreUint64(r.word(pos, i), w)
+	}
+}
+
+// read appends the n-byte message of the slot for pos to buf.
+func (r *Ring) read(pos uint64, n int, buf []byte) []byte {
+	for i := 0; i*8 < n; i++ {
+		w := atomic.LoadUint64(r.word(pos, i))
+		for j := 0; j < 8 && i*8+j < n; j++ {
+			buf = append(buf, byte(w>>(8*j)))
+		}
+	}
+	return buf
+}
+
+func (r *Ring) recovered() {
+	stats := r.region.Cell(cellStats)
+	for old := stats.Load(); ; {
+		prev, ok := stats.AcqRel(old, dwcas.Uint128{Lo: old.Lo + 1})
+		if ok {
+			return
+		}
+		old = prev
+	}
+}
+
+// advance moves a cursor from pos to pos+1 unless someone already did.
+func advance(cursor *dwcas.Uint128, pos uint64) {
+	cursor.AcqRel(dwcas.Uint128{Lo: pos}, dwcas.Uint128{Lo: pos + 1})
+}
+
+func backoff(i int) {
+	switch {
+	case i < 64:
+	case i < 256:
+		runtime.Gosched()
+	default:
+		time.Sleep(50 * time.Microsecond)
+	}
+}
@@ -0,0 +1,331 @@
+//go:build linux && (amd64 || arm64)
+
+package ring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"testing"
+
+	"code.hybscloud.com/dwcas/shm"
+)
+
+// Helper processes re-run the test binary with these variables set; see
+// TestMain. The ring is inherited as file 3.
+const (
+	helperEnv   = "DWCAS_RING_HELPER" // "send", "crash-send" or "crash-receive"
+	helperCount = "DWCAS_RING_COUNT"
+	helperBase  = "DWCAS_RING_BASE"
+)
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(helperEnv); mode != "" {
+		os.Exit(helper(mode))
+	}
+	os.Exit(m.Run())
+}
+
+func helper(mode string) int {
+	r, err := OpenFile(os.NewFile(3, "ring"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	count, _ := strconv.Atoi(os.Getenv(helperCount))
+	base, _ := strconv.Atoi(os.Getenv(helperBase))
+	switch mode {
+	case "send":
+		p := r.Producer()
+		for i := 0; i < count; i++ {
+			p.Send(message(base + i))
+		}
+	case "crash-send":
+		// Die holding a claimed slot, as a crash between claim and commit.
+		testHookClaimed = func() { os.Exit(0) }
+		r.Producer().Send(message(base))
+	case "crash-receive":
+		testHookClaimed = func() { os.Exit(0) }
+		r.Consumer().Receive(nil)
+	}
+	return 0
+}
+
+// message returns a message of varying length that encodes i.
+func message(i int) []byte {
+	msg := binary.LittleEndian.AppendUint64(nil, uint64(i))
+	return append(msg, bytes.Repeat([]byte{byte(i)}, i%40)...)
+}
+
+func decode(t testing.TB, msg []byte) int {
+	i := int(binary.LittleEndian.Uint64(msg))
+	if !bytes.Equal(msg, message(i)) {
+		t.Fatalf("corrupt message %x", msg)
+	}
+	return i
+}
+
+func runHelper(t *testing.T, f *os.File, mode string, count, base int) *exec.Cmd {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(),
+		helperEnv+"="+mode,
+		helperCount+"="+strconv.Itoa(count),
+		helperBase+"="+strconv.Itoa(base))
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{f}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func newRing(t *testing.T, slots, maxMessage int) (*Ring, *os.File) {
+	r, f, err := CreateAnonymous(slots, maxMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+		f.Close()
+	})
+	return r, f
+}
+
+func TestRing_FIFO(t *testing.T) {
+	r, _ := newRing(t, 4, 50)
+	if r.MaxMessage() != 64 || r.Slots() != 4 {
+		t.Fatalf("geometry %d slots of %d bytes", r.Slots(), r.MaxMessage())
+	}
+	p, c := r.Producer(), r.Consumer()
+	if _, ok := c.TryReceive(nil); ok {
+		t.Fatalf("received from an empty ring")
+	}
+	for lap := 0; lap < 3; lap++ {
+		for i := 0; i < 4; i++ {
+			if !p.TrySend(message(lap*4 + i)) {
+				t.Fatalf("TrySend %d failed with room", i)
+			}
+		}
+		if p.TrySend(nil) {
+			t.Fatalf("TrySend succeeded on a full ring")
+		}
+		for i := 0; i < 4; i++ {
+			msg, ok := c.TryReceive(nil)
+			if !ok || decode(t, msg) != lap*4+i {
+				t.Fatalf("TryReceive = %x, %v; want message %d", msg, ok, lap*4+i)
+			}
+		}
+	}
+	p.Send(nil)
+	if msg, ok := c.TryReceive([]byte("x")); !ok || string(msg) != "x" {
+		t.Fatalf("empty message: %q, %v", msg, ok)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("oversized message did not panic")
+			}
+		}()
+		p.TrySend(make([]byte, 65))
+	}()
+}
+
+func TestOpen(t *testing.T) {
+	name := fmt.Sprintf("dwcas-ring-test-%d", os.Getpid())
+	defer shm.Unlink(name)
+	r, err := Create(name, 8, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	o, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	r.Producer().Send([]byte("hello"))
+	if msg := o.Consumer().Receive(nil); string(msg) != "hello" {
+		t.Fatalf("Receive = %q", msg)
+	}
+
+	plain := name + "-plain"
+	defer shm.Unlink(plain)
+	region, err := shm.Create(plain, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	region.Close()
+	if _, err := Open(plain); err != shm.ErrNotReady {
+		t.Fatalf("Open of a region without a ring: %v", err)
+	}
+}
+
+func TestRing_ConcurrentExactlyOnce(t *testing.T) {
+	const (
+		producers = 4
+		consumers = 4
+	)
+	perProducer := 5000
+	if testing.Short() {
+		perProducer = 500
+	}
+	r, _ := newRing(t, 16, 48)
+	seen := make([]int32, producers*perProducer)
+	var wg sync.WaitGroup
+	for w := 0; w < producers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := r.Producer()
+			for i := 0; i < perProducer; i++ {
+				p.Send(message(w*perProducer + i))
+			}
+		}()
+	}
+	var mu sync.Mutex
+	var received int
+	var cwg sync.WaitGroup
+	for w := 0; w < consumers; w++ {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			c := r.Consumer()
+			var buf []byte
+			for {
+				mu.Lock()
+				done := received == len(seen)
+				mu.Unlock()
+				if done {
+					return
+				}
+				msg, ok := c.TryReceive(buf[:0])
+				if !ok {
+					backoff(100)
+					continue
+				}
+				buf = msg
+				i := decode(t, msg)
+				mu.Lock()
+				seen[i]++
+				received++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	cwg.Wait()
+	for i, n := range seen {
+		if n != 1 {
+			t.Fatalf("message %d received %d times", i, n)
+		}
+	}
+}
+
+func TestRing_CrossProcess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns processes")
+	}
+	const procs, count = 3, 3000
+	r, f := newRing(t, 8, 48)
+	var cmds []*exec.Cmd
+	for i := 0; i < procs; i++ {
+		cmds = append(cmds, runHelper(t, f, "send", count, i*count))
+	}
+	c := r.Consumer()
+	seen := make([]bool, procs*count)
+	next := make([]int, procs)
+	for n := 0; n < procs*count; n++ {
+		i := decode(t, c.Receive(nil))
+		if seen[i] {
+			t.Fatalf("message %d received twice", i)
+		}
+		seen[i] = true
+		// Messages from one producer arrive in the order it sent them.
+		if i != next[i/count]+i/count*count {
+			t.Fatalf("message %d out of order", i)
+		}
+		next[i/count]++
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRing_RecoversCrashedProducer(t *testing.T) {
+	r, f := newRing(t, 4, 16)
+	p, c := r.Producer(), r.Consumer()
+	p.Send(message(1))
+	if err := runHelper(t, f, "crash-send", 1, 80).Wait(); err != nil {
+		t.Fatal(err)
+	}
+	p.Send(message(2))
+	for _, want := range []int{1, 2} {
+		if i := decode(t, c.Receive(nil)); i != want {
+			t.Fatalf("received message %d, want %d", i, want)
+		}
+	}
+	if _, ok := c.TryReceive(nil); ok {
+		t.Fatalf("half-written message was delivered")
+	}
+	if r.Recovered() != 1 {
+		t.Fatalf("Recovered = %d, want 1", r.Recovered())
+	}
+}
+
+func TestRing_RecoversCrashedConsumer(t *testing.T) {
+	r, f := newRing(t, 2, 16)
+	p, c := r.Producer(), r.Consumer()
+	p.Send(message(1))
+	p.Send(message(2))
+	if err := runHelper(t, f, "crash-receive", 0, 0).Wait(); err != nil {
+		t.Fatal(err)
+	}
+	// Message 1 died with its consumer; its slot must be reusable.
+	if !p.TrySend(message(3)) {
+		t.Fatalf("slot held by a dead consumer was not recovered")
+	}
+	if r.Recovered() != 1 {
+		t.Fatalf("Recovered = %d, want 1", r.Recovered())
+	}
+	for _, want := range []int{2, 3} {
+		if i := decode(t, c.Receive(nil)); i != want {
+			t.Fatalf("received message %d, want %d", i, want)
+		}
+	}
+}
+
+func TestAlive_ReusedPID(t *testing.T) {
+	owner := ownerWord(stateWriting)
+	if !alive(owner) {
+		t.Fatalf("own owner word %#x reported dead", owner)
+	}
+	// The same PID with another start time belongs to an earlier process.
+	reused := owner ^ 1<<32
+	if alive(reused) {
+		t.Fatalf("owner word %#x with a stale start time reported alive", reused)
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	if cps := checkSize("Create", 1, 1<<31); cps != 1+1<<27 {
+		t.Fatalf("checkSize(1, 1<<31) = %d, want %d", cps, 1+1<<27)
+	}
+	for _, tc := range []struct{ slots, maxMessage int }{
+		{0, 16}, {1, 0}, {1, 1<<31 + 1},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("checkSize(%d, %d) did not panic", tc.slots, tc.maxMessage)
+				}
+			}()
+			checkSize("Create", tc.slots, tc.maxMessage)
+		}()
+	}
+}
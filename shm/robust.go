@@ -0,0 +1,114 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/internal/proc"
+)
+
+// ErrOwnerDied is returned by [RobustMutex.Lock] and [RobustMutex.TryLock]
+// when the mutex was taken over from a process that died holding it. The
+// caller holds the mutex, but the data it protects may be inconsistent and
+// should be repaired before the mutex is unlocked.
+var ErrOwnerDied = errors.New("shm: mutex owner died")
+
+// RobustMutex is a mutual exclusion lock for processes sharing a region,
+// kept in one cell as (owner PID, owner start time). The zero cell is
+// unlocked.
+//
+// The start time identifies the owner together with its PID, so a new process
+// that reuses the PID of a dead owner is not mistaken for it. When a waiter
+// finds the owner gone, it takes the mutex over with a CAS and reports
+// [ErrOwnerDied]. Liveness is checked through /proc, so all processes using a
+// mutex must share a PID namespace; elsewhere than Linux, owners are always
+// assumed alive.
+//
+// The owner is a process, not a goroutine: goroutines of one process exclude
+// each other, but any of them may unlock a mutex another one locked. Waiters
+// spin, then yield, then sleep between attempts, since other processes cannot
+// wake them.
+type RobustMutex struct {
+	cell *dwcas.Uint128
+}
+
+// NewRobustMutex returns a mutex kept in cell, typically a cell of a
+// [Region].
+func NewRobustMutex(cell *dwcas.Uint128) *RobustMutex {
+	return &RobustMutex{cell: cell}
+}
+
+var (
+	selfOnce sync.Once
+	self     dwcas.Uint128 // {Lo: PID, Hi: start time}
+)
+
+// owner returns the cell value of a mutex held by this process.
+func owner() dwcas.Uint128 {
+	selfOnce.Do(func() { self = proc.Self() })
+	return self
+}
+
+// Lock locks m, waiting until it is available. It returns nil, or
+// [ErrOwnerDied] if m was taken over from a dead owner; m is locked in both
+// cases.
+func (m *RobustMutex) Lock() error {
+	for i := 0; ; i++ {
+		ok, err := m.try(i >= robustSpins)
+		if ok {
+			return err
+		}
+		switch {
+		case i < robustSpins:
+		case i < robustYields:
+			runtime.Gosched()
+		default:
+			time.Sleep(50 * time.Microsecond)
+		}
+	}
+}
+
+// Spin budget of Lock: busy retries, then retries that yield, then sleeps.
+// Owner liveness is checked once the busy retries are spent.
+const (
+	robustSpins  = 64
+	robustYields = 256
+)
+
+// TryLock locks m if it is unlocked or its owner is dead, and reports whether
+// it did. err is [ErrOwnerDied] if m was taken over from a dead owner.
+func (m *RobustMutex) TryLock() (locked bool, err error) {
+	return m.try(true)
+}
+
+// try makes one attempt to lock m, checking whether a holder is alive if
+// check is set.
+func (m *RobustMutex) try(check bool) (bool, error) {
+	me := owner()
+	old := m.cell.Load()
+	if old == (dwcas.Uint128{}) {
+		_, ok := m.cell.AcqRel(old, me)
+		return ok, nil
+	}
+	if !check || old == me || proc.Alive(old) {
+		return false, nil
+	}
+	if _, ok := m.cell.AcqRel(old, me); !ok {
+		return false, nil
+	}
+	return true, ErrOwnerDied
+}
+
+// Unlock unlocks m. It panics if m is not locked by this process.
+func (m *RobustMutex) Unlock() {
+	if _, ok := m.cell.AcqRel(owner(), dwcas.Uint128{}); !ok {
+		panic("shm: RobustMutex: Unlock of mutex not held by this process")
+	}
+}
@@ -0,0 +1,193 @@
+//go:build linux && (amd64 || arm64)
+
+package shm_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/shm"
+)
+
+// mutexHelper runs in a helper process. In mode "hold" it locks the mutex in
+// cell, reports "locked" on stdout and waits to be killed. In mode "add" it
+// increments the Lo of cell+1 iters times, non-atomically, under the mutex in
+// cell.
+func mutexHelper(mode string, r *shm.Region, cell, iters int) int {
+	m := shm.NewRobustMutex(r.Cell(cell))
+	switch mode {
+	case "hold":
+		if err := m.Lock(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Println("locked")
+		time.Sleep(time.Hour)
+	case "add":
+		counter := &r.Cell(cell + 1).Lo
+		for i := 0; i < iters; i++ {
+			if err := m.Lock(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			v := atomic.LoadUint64(counter)
+			atomic.StoreUint64(counter, v+1)
+			m.Unlock()
+		}
+	}
+	return 0
+}
+
+func mutexHelperCmd(t *testing.T, name, mode string, cell, iters int) *exec.Cmd {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(),
+		helperEnv+"="+name,
+		helperMode+"="+mode,
+		helperCell+"="+strconv.Itoa(cell),
+		helperIter+"="+strconv.Itoa(iters))
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// holdInChild starts a helper that locks the mutex in cell and returns once
+// it holds it.
+func holdInChild(t *testing.T, name string, cell int) *exec.Cmd {
+	cmd := mutexHelperCmd(t, name, "hold", cell, 0)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	if line, err := bufio.NewReader(out).ReadString('\n'); err != nil || line != "locked\n" {
+		t.Fatalf("helper: %q, %v", line, err)
+	}
+	return cmd
+}
+
+func TestRobustMutex_InProcess(t *testing.T) {
+	m := shm.NewRobustMutex(dwcas.New(0, 0))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := m.TryLock(); ok || err != nil {
+		t.Fatalf("TryLock of a held mutex = %v, %v", ok, err)
+	}
+	m.Unlock()
+	if ok, err := m.TryLock(); !ok || err != nil {
+		t.Fatalf("TryLock of a free mutex = %v, %v", ok, err)
+	}
+	m.Unlock()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Unlock of an unlocked mutex did not panic")
+			}
+		}()
+		m.Unlock()
+	}()
+}
+
+func TestRobustMutex_ReusedPIDIsNotOwner(t *testing.T) {
+	// The parent process is alive, but with another start time it stands
+	// for a dead owner whose PID was reused.
+	cell := dwcas.New(uint64(os.Getppid()), 1)
+	m := shm.NewRobustMutex(cell)
+	if ok, err := m.TryLock(); !ok || err != shm.ErrOwnerDied {
+		t.Fatalf("TryLock over a reused PID = %v, %v", ok, err)
+	}
+	m.Unlock()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Unlock of a mutex held by another process did not panic")
+			}
+		}()
+		shm.NewRobustMutex(dwcas.New(uint64(os.Getppid()), 1)).Unlock()
+	}()
+}
+
+func TestRobustMutex_KilledOwner(t *testing.T) {
+	for _, reap := range []bool{true, false} {
+		t.Run(fmt.Sprintf("reaped=%v", reap), func(t *testing.T) {
+			name := regionName(t)
+			r, err := shm.Create(name, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			m := shm.NewRobustMutex(r.Cell(0))
+
+			cmd := holdInChild(t, name, 0)
+			if ok, err := m.TryLock(); ok || err != nil {
+				t.Fatalf("TryLock while a live child holds the mutex = %v, %v", ok, err)
+			}
+			if err := cmd.Process.Kill(); err != nil {
+				t.Fatal(err)
+			}
+			if reap {
+				cmd.Wait()
+			} else {
+				// Leave a zombie until cleanup, but let it die first.
+				for {
+					stat, _ := os.ReadFile(fmt.Sprintf("/proc/%d/stat", cmd.Process.Pid))
+					if len(stat) == 0 || bytes.Contains(stat, []byte(") Z ")) {
+						break
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}
+			if err := m.Lock(); err != shm.ErrOwnerDied {
+				t.Fatalf("Lock after the owner was killed = %v", err)
+			}
+			m.Unlock()
+			if err := m.Lock(); err != nil {
+				t.Fatalf("Lock after recovery = %v", err)
+			}
+			m.Unlock()
+		})
+	}
+}
+
+func TestRobustMutex_ExcludesProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns processes")
+	}
+	const procs, iters = 3, 2000
+	name := regionName(t)
+	r, err := shm.Create(name, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var cmds []*exec.Cmd
+	for i := 0; i < procs; i++ {
+		cmd := mutexHelperCmd(t, name, "add", 0, iters)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("helper: %v", err)
+		}
+	}
+	if n := r.Cell(1).Load().Lo; n != procs*iters {
+		t.Fatalf("counter = %d, want %d: increments were lost", n, procs*iters)
+	}
+}
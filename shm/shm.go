@@ -0,0 +1,148 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package shm maps named shared-memory regions that hold [dwcas.Uint128]
+// cells, so that processes on the same host can coordinate with 128-bit CAS.
+//
+// A region is a file in /dev/shm (see [Create] and [Open]) or an anonymous
+// memfd (see [CreateAnonymous]) whose file is passed to other processes and
+// mapped with [OpenFile]. It starts with a header of [HeaderSize] bytes:
+//
+//	offset  size  field
+//	0       8     magic "DWCASSHM", written last by the creator
+//	8       4     layout version ([LayoutVersion])
+//	12      4     header size
+//	16      8     mapping size in bytes
+//	24      8     number of cells
+//	32      32    reserved, zero
+//
+// Cell i follows at offset HeaderSize + 16*i. Mappings are page aligned, so
+// every cell is 16-byte aligned at the same offset in every process. The
+// mapping carries 16 bytes of slack after the last cell so that
+// [dwcas.PlaceAlignedUint128] can place it.
+//
+// Multi-byte header fields are in host byte order; a region is shared by
+// processes on one host.
+//
+// The CAS instructions work on shared mappings as on process memory, but a
+// process that dies while holding a lock built on the cells leaves it held.
+// [RobustMutex] detects a dead owner and lets another process take over.
+package shm
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+	"unsafe"
+
+	"code.hybscloud.com/dwcas"
+)
+
+const (
+	// HeaderSize is the size of the region header in bytes; the first cell
+	// starts there.
+	HeaderSize = 64
+
+	// LayoutVersion is the version of the header and cell layout written by
+	// this package. Open refuses regions with a different version.
+	LayoutVersion = 1
+
+	// magic is "DWCASSHM" read as a little-endian uint64.
+	magic = 0x4d48535341435744
+)
+
+var (
+	// ErrNotReady is returned when the region is smaller than a header or
+	// its creator has not finished writing the header.
+	ErrNotReady = errors.New("shm: region is not initialized")
+
+	// ErrBadMagic is returned when the region was not created by this
+	// package.
+	ErrBadMagic = errors.New("shm: bad magic")
+
+	// ErrLayoutVersion is returned when the region uses another layout
+	// version.
+	ErrLayoutVersion = errors.New("shm: unsupported layout version")
+
+	// ErrTruncated is returned when the file is smaller than its header
+	// says.
+	ErrTruncated = errors.New("shm: region is truncated")
+
+	// ErrInvalidName is returned for a name that is empty or contains '/'.
+	ErrInvalidName = errors.New("shm: invalid name")
+)
+
+// Region is a mapped shared-memory region.
+type Region struct {
+	unmap func() error
+	mem   []byte
+	cells int
+}
+
+// Cells returns the number of cells in r.
+func (r *Region) Cells() int {
+	return r.cells
+}
+
+// Cell returns cell i. The pointer is valid until r is closed.
+//
+// Cell panics if i is out of range.
+func (r *Region) Cell(i int) *dwcas.Uint128 {
+	if uint(i) >= uint(r.cells) {
+		panic("shm: Cell: index out of range")
+	}
+	_, p := dwcas.PlaceAlignedUint128(r.mem, HeaderSize+16*i)
+	return p
+}
+
+// Close unmaps r. Cells obtained from r must not be used afterwards. Close does
+// not remove a named region; see [Unlink].
+func (r *Region) Close() error {
+	if r.unmap == nil {
+		return nil
+	}
+	err := r.unmap()
+	r.unmap, r.mem, r.cells = nil, nil, 0
+	return err
+}
+
+// mappingSize returns the mapping size for the given number of cells, rounded
+// up to whole pages.
+func mappingSize(cells, pageSize int) int {
+	n := HeaderSize + 16*cells + 16
+	return (n + pageSize - 1) / pageSize * pageSize
+}
+
+// initHeader writes the header of a new region and publishes it by storing the
+// magic last.
+func initHeader(mem []byte, cells int) {
+	h := binary.NativeEndian
+	h.PutUint32(mem[8:], LayoutVersion)
+	h.PutUint32(mem[12:], HeaderSize)
+	h.PutUint64(mem[16:], uint64(len(mem)))
+	h.PutUint64(mem[24:], uint64(cells))
+	atomic.StoreUint64((*uint64)(unsafe.Pointer(&mem[0])), magic)
+}
+
+// checkHeader validates the header of an existing region of fileSize bytes
+// and returns its mapping size and number of cells. mem must map at least
+// HeaderSize bytes.
+func checkHeader(mem []byte, fileSize int64) (size, cells int, err error) {
+	switch atomic.LoadUint64((*uint64)(unsafe.Pointer(&mem[0]))) {
+	case magic:
+	case 0:
+		return 0, 0, ErrNotReady
+	default:
+		return 0, 0, ErrBadMagic
+	}
+	h := binary.NativeEndian
+	if h.Uint32(mem[8:]) != LayoutVersion || h.Uint32(mem[12:]) != HeaderSize {
+		return 0, 0, ErrLayoutVersion
+	}
+	sz, n := h.Uint64(mem[16:]), h.Uint64(mem[24:])
+	if sz > uint64(fileSize) || sz < HeaderSize+16 || n > (sz-HeaderSize-16)/16 {
+		return 0, 0, ErrTruncated
+	}
+	return int(sz), int(n), nil
+}
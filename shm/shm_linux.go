@@ -0,0 +1,155 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux && (amd64 || arm64)
+
+package shm
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// dir is where named regions live.
+const dir = "/dev/shm/"
+
+// mfdCloexec is MFD_CLOEXEC, which the syscall package does not define.
+const mfdCloexec = 0x1
+
+func path(name string) (string, error) {
+	if name == "" || strings.ContainsRune(name, '/') {
+		return "", ErrInvalidName
+	}
+	return dir + name, nil
+}
+
+// Create creates the named region with the given number of cells, all zero.
+// It fails if the name is taken.
+//
+// Create panics if cells is negative.
+func Create(name string, cells int) (*Region, error) {
+	if cells < 0 {
+		panic("shm: Create: negative cell count")
+	}
+	p, err := path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := create(f, cells)
+	if err != nil {
+		os.Remove(p)
+		return nil, err
+	}
+	return r, nil
+}
+
+// CreateAnonymous creates a region backed by a memfd, which has no name in
+// the file system, and returns the region and the memfd. Other processes map
+// the region by inheriting or receiving the file and calling [OpenFile]. name
+// only labels the memfd in /proc. Like every file the os package opens, the
+// memfd is close-on-exec; pass it to a child through the ExtraFiles field of
+// [os/exec.Cmd]. The caller closes the file when it is no longer needed; the
+// mapping stays valid.
+//
+// CreateAnonymous panics if cells is negative.
+func CreateAnonymous(name string, cells int) (*Region, *os.File, error) {
+	if cells < 0 {
+		panic("shm: CreateAnonymous: negative cell count")
+	}
+	b, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fd, _, errno := syscall.Syscall(sysMemfdCreate, uintptr(unsafe.Pointer(b)), mfdCloexec, 0)
+	if errno != 0 {
+		return nil, nil, os.NewSyscallError("memfd_create", errno)
+	}
+	f := os.NewFile(fd, "memfd:"+name)
+	r, err := create(f, cells)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return r, f, nil
+}
+
+// Open maps the named region created by [Create].
+func Open(name string) (*Region, error) {
+	p, err := path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return OpenFile(f)
+}
+
+// OpenFile maps the region in f, which may be a file under /dev/shm or a memfd
+// from [CreateAnonymous]. The mapping stays valid after f is closed.
+func OpenFile(f *os.File) (*Region, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < HeaderSize {
+		return nil, ErrNotReady
+	}
+	fd := int(f.Fd())
+	head, err := syscall.Mmap(fd, 0, HeaderSize, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, os.NewSyscallError("mmap", err)
+	}
+	size, cells, err := checkHeader(head, fi.Size())
+	syscall.Munmap(head)
+	if err != nil {
+		return nil, err
+	}
+	return mapRegion(fd, size, cells)
+}
+
+// Unlink removes the named region. Processes that have it mapped keep their
+// mappings.
+func Unlink(name string) error {
+	p, err := path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// create sizes the new, empty file f for cells, maps it and writes the header.
+func create(f *os.File, cells int) (*Region, error) {
+	size := mappingSize(cells, os.Getpagesize())
+	if err := f.Truncate(int64(size)); err != nil {
+		return nil, err
+	}
+	r, err := mapRegion(int(f.Fd()), size, cells)
+	if err != nil {
+		return nil, err
+	}
+	initHeader(r.mem, cells)
+	return r, nil
+}
+
+func mapRegion(fd, size, cells int) (*Region, error) {
+	mem, err := syscall.Mmap(fd, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, os.NewSyscallError("mmap", err)
+	}
+	return &Region{
+		unmap: func() error { return syscall.Munmap(mem) },
+		mem:   mem,
+		cells: cells,
+	}, nil
+}
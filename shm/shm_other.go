@@ -0,0 +1,41 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux || !(amd64 || arm64)
+
+package shm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Create is only supported on linux/amd64 and linux/arm64; elsewhere it
+// returns an error wrapping [errors.ErrUnsupported].
+func Create(name string, cells int) (*Region, error) {
+	return nil, errUnsupported
+}
+
+// CreateAnonymous is only supported on linux/amd64 and linux/arm64.
+func CreateAnonymous(name string, cells int) (*Region, *os.File, error) {
+	return nil, nil, errUnsupported
+}
+
+// Open is only supported on linux/amd64 and linux/arm64.
+func Open(name string) (*Region, error) {
+	return nil, errUnsupported
+}
+
+// OpenFile is only supported on linux/amd64 and linux/arm64.
+func OpenFile(f *os.File) (*Region, error) {
+	return nil, errUnsupported
+}
+
+// Unlink is only supported on linux/amd64 and linux/arm64.
+func Unlink(name string) error {
+	return errUnsupported
+}
+
+var errUnsupported = fmt.Errorf("shm: requires linux/amd64 or linux/arm64: %w", errors.ErrUnsupported)
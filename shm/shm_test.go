@@ -0,0 +1,274 @@
+//go:build linux && (amd64 || arm64)
+
+package shm_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+	"code.hybscloud.com/dwcas/shm"
+)
+
+// Helper processes re-run the test binary with these variables set; see
+// TestMain.
+const (
+	helperEnv  = "DWCAS_SHM_HELPER" // region name, or "fd" for an inherited memfd
+	helperCell = "DWCAS_SHM_CELL"
+	helperIter = "DWCAS_SHM_ITERS"
+	helperMode = "DWCAS_SHM_MODE" // empty, or a RobustMutex mode; see robust_test.go
+)
+
+func TestMain(m *testing.M) {
+	if name := os.Getenv(helperEnv); name != "" {
+		os.Exit(helper(name))
+	}
+	os.Exit(m.Run())
+}
+
+// helper adds 1 to the Lo of a cell with a CAS loop, keeping Hi equal to
+// Lo's witness, and reports a torn value through its exit status.
+func helper(name string) int {
+	var r *shm.Region
+	var err error
+	if name == "fd" {
+		r, err = shm.OpenFile(os.NewFile(3, "memfd"))
+	} else {
+		r, err = shm.Open(name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer r.Close()
+	cell, _ := strconv.Atoi(os.Getenv(helperCell))
+	iters, _ := strconv.Atoi(os.Getenv(helperIter))
+	if mode := os.Getenv(helperMode); mode != "" {
+		return mutexHelper(mode, r, cell, iters)
+	}
+	p := r.Cell(cell)
+	old := p.Load()
+	for i := 0; i < iters; i++ {
+		for {
+			if old.Hi != witness(old.Lo) {
+				fmt.Fprintf(os.Stderr, "torn value (%#x, %#x)\n", old.Lo, old.Hi)
+				return 3
+			}
+			next := dwcas.Uint128{Lo: old.Lo + 1, Hi: witness(old.Lo + 1)}
+			prev, ok := p.AcqRel(old, next)
+			if ok {
+				old = next
+				break
+			}
+			old = prev
+		}
+	}
+	return 0
+}
+
+func witness(v uint64) uint64 {
+	if v == 0 {
+		return 0
+	}
+	return v*0x9e3779b97f4a7c15 ^ 0xd1b54a32d192ed03
+}
+
+func regionName(t *testing.T) string {
+	name := fmt.Sprintf("dwcas-test-%d-%s", os.Getpid(), strings.ReplaceAll(t.Name(), "/", "-"))
+	t.Cleanup(func() { shm.Unlink(name) })
+	return name
+}
+
+func helpers(t *testing.T, n int, name string, cell, iters int, files ...*os.File) []*exec.Cmd {
+	cmds := make([]*exec.Cmd, n)
+	for i := range cmds {
+		cmd := exec.Command(os.Args[0])
+		cmd.Env = append(os.Environ(),
+			helperEnv+"="+name,
+			helperCell+"="+strconv.Itoa(cell),
+			helperIter+"="+strconv.Itoa(iters))
+		cmd.Stderr = os.Stderr
+		cmd.ExtraFiles = files
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds[i] = cmd
+	}
+	return cmds
+}
+
+func wait(t *testing.T, cmds []*exec.Cmd) {
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("helper: %v", err)
+		}
+	}
+}
+
+func TestCreateOpen(t *testing.T) {
+	name := regionName(t)
+	r, err := shm.Create(name, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := shm.Create(name, 1); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("Create of an existing name: %v", err)
+	}
+	r.Cell(99).AcqRel(dwcas.Uint128{}, dwcas.Uint128{Lo: 1, Hi: 2})
+
+	o, err := shm.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	if o.Cells() != 100 {
+		t.Fatalf("Cells = %d, want 100", o.Cells())
+	}
+	if v := o.Cell(99).Load(); v != (dwcas.Uint128{Lo: 1, Hi: 2}) {
+		t.Fatalf("second mapping reads %v", v)
+	}
+	if _, ok := o.Cell(0).AcqRel(dwcas.Uint128{}, dwcas.Uint128{Lo: 7}); !ok {
+		t.Fatalf("CAS on a fresh cell failed")
+	}
+	if v := r.Cell(0).Load(); v.Lo != 7 {
+		t.Fatalf("first mapping reads %v", v)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Cell(100) did not panic")
+			}
+		}()
+		r.Cell(100)
+	}()
+}
+
+func TestOpen_RejectsForeignFiles(t *testing.T) {
+	if _, err := shm.Open("a/b"); err != shm.ErrInvalidName {
+		t.Fatalf("Open(\"a/b\"): %v", err)
+	}
+	if _, err := shm.Open(regionName(t)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Open of a missing region: %v", err)
+	}
+
+	name := regionName(t)
+	path := "/dev/shm/" + name
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shm.Open(name); err != shm.ErrNotReady {
+		t.Fatalf("empty file: %v", err)
+	}
+	if err := os.WriteFile(path, make([]byte, 4096), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shm.Open(name); err != shm.ErrNotReady {
+		t.Fatalf("zero header: %v", err)
+	}
+	junk := make([]byte, 4096)
+	copy(junk, "not a region")
+	if err := os.WriteFile(path, junk, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shm.Open(name); err != shm.ErrBadMagic {
+		t.Fatalf("foreign file: %v", err)
+	}
+
+	valid := name + "-valid"
+	r, err := shm.Create(valid, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	defer shm.Unlink(valid)
+	hdr, err := os.ReadFile("/dev/shm/" + valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Claim a cell count the file cannot hold, then a future layout.
+	hdr[24] = 0xff
+	os.WriteFile(path, hdr, 0o600)
+	if _, err := shm.Open(name); err != shm.ErrTruncated {
+		t.Fatalf("oversized cell count: %v", err)
+	}
+	hdr[24] = 1
+	hdr[8]++
+	os.WriteFile(path, hdr, 0o600)
+	if _, err := shm.Open(name); err != shm.ErrLayoutVersion {
+		t.Fatalf("layout version: %v", err)
+	}
+}
+
+func TestMultiProcess_NamedRegion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns processes")
+	}
+	const procs, iters = 4, 20000
+	name := regionName(t)
+	r, err := shm.Create(name, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// The parent takes part too, on the same cell.
+	cmds := helpers(t, procs, name, 2, iters)
+	p := r.Cell(2)
+	for i := 0; i < iters; i++ {
+		for old := p.Load(); ; {
+			prev, ok := p.AcqRel(old, dwcas.Uint128{Lo: old.Lo + 1, Hi: witness(old.Lo + 1)})
+			if ok {
+				break
+			}
+			old = prev
+		}
+	}
+	wait(t, cmds)
+	if v := p.Load(); v.Lo != (procs+1)*iters || v.Hi != witness(v.Lo) {
+		t.Fatalf("cell = (%d, %#x), want %d increments", v.Lo, v.Hi, (procs+1)*iters)
+	}
+	if r.Cell(0).Load() != (dwcas.Uint128{}) || r.Cell(1).Load() != (dwcas.Uint128{}) {
+		t.Fatalf("helpers wrote outside their cell")
+	}
+}
+
+func TestCreateAnonymous_CloseOnExec(t *testing.T) {
+	r, f, err := shm.CreateAnonymous("dwcas-test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer f.Close()
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), syscall.F_GETFD, 0)
+	if errno != 0 {
+		t.Fatal(errno)
+	}
+	if flags&syscall.FD_CLOEXEC == 0 {
+		t.Fatalf("memfd is not close-on-exec")
+	}
+}
+
+func TestMultiProcess_Memfd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns processes")
+	}
+	const procs, iters = 3, 10000
+	r, f, err := shm.CreateAnonymous("dwcas-test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	cmds := helpers(t, procs, "fd", 0, iters, f)
+	f.Close()
+	wait(t, cmds)
+	if v := r.Cell(0).Load(); v.Lo != procs*iters {
+		t.Fatalf("cell = %d, want %d", v.Lo, procs*iters)
+	}
+}
@@ -0,0 +1,114 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package snapshot provides a wait-free atomic snapshot object: an array of
+// uint64 components, each updated by a single writer, that readers can scan
+// as a consistent whole while writers keep running.
+//
+// Each component is a [dwcas.Uint128] holding (value, sequence), so a reader
+// sees a value and the number of the update that wrote it in one load. A scan
+// collects all components twice; if no sequence changed in between, the
+// first collect is a snapshot. Otherwise it retries, and once some component
+// has been seen to change twice, its writer has performed a complete update
+// during the scan. Every update embeds a scan of its own (Afek et al.), so the
+// reader returns that writer's embedded view. With n components a scan
+// therefore finishes within n+2 collects.
+package snapshot
+
+import (
+	"sync/atomic"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// view is the scan a writer embedded in one of its updates.
+type view struct {
+	seq    uint64 // sequence number of the update
+	values []uint64
+}
+
+// Object is an atomic snapshot object of a fixed number of components.
+type Object struct {
+	cells []dwcas.Uint128 // {Lo: value, Hi: sequence}
+	views []atomic.Pointer[view]
+}
+
+// New returns an object of n components, all zero.
+//
+// New panics if n is negative.
+func New(n int) *Object {
+	if n < 0 {
+		panic("snapshot: New: negative length")
+	}
+	return &Object{cells: dwcas.NewSlice(n), views: make([]atomic.Pointer[view], n)}
+}
+
+// Len returns the number of components.
+func (o *Object) Len() int {
+	return len(o.cells)
+}
+
+// Load returns component i.
+func (o *Object) Load(i int) uint64 {
+	return o.cells[i].Load().Lo
+}
+
+// Update sets component i to v.
+//
+// Components have a single writer: at most one goroutine may update a given
+// component at a time. Different components may be updated concurrently, and
+// one goroutine may own several components. Update performs a scan and is
+// wait-free.
+func (o *Object) Update(i int, v uint64) {
+	c := &o.cells[i]
+	cur := c.Load()
+	vals := o.Scan()
+	o.views[i].Store(&view{seq: cur.Hi + 1, values: vals})
+	if _, ok := c.Release(cur, dwcas.Uint128{Lo: v, Hi: cur.Hi + 1}); !ok {
+		panic("snapshot: Update: concurrent writers on one component")
+	}
+}
+
+// Scan returns the values of all components at a single instant between the
+// call and its return. The returned slice is freshly allocated.
+func (o *Object) Scan() []uint64 {
+	n := len(o.cells)
+	prev := o.collect(make([]dwcas.Uint128, n))
+	start := make([]uint64, n)
+	for j := range prev {
+		start[j] = prev[j].Hi
+	}
+	next := make([]dwcas.Uint128, n)
+	for {
+		o.collect(next)
+		clean := true
+		for j := range next {
+			if next[j].Hi == prev[j].Hi {
+				continue
+			}
+			clean = false
+			if next[j].Hi >= start[j]+2 {
+				// Component j completed an update, including its
+				// embedded scan, entirely within this scan.
+				return append([]uint64(nil), o.views[j].Load().values...)
+			}
+		}
+		if clean {
+			vals := make([]uint64, n)
+			for j := range next {
+				vals[j] = next[j].Lo
+			}
+			return vals
+		}
+		prev, next = next, prev
+	}
+}
+
+// collect loads every component into dst.
+func (o *Object) collect(dst []dwcas.Uint128) []dwcas.Uint128 {
+	for j := range o.cells {
+		dst[j] = o.cells[j].Load()
+	}
+	return dst
+}
@@ -0,0 +1,126 @@
+package snapshot_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"code.hybscloud.com/dwcas/snapshot"
+)
+
+func TestObject_Sequential(t *testing.T) {
+	o := snapshot.New(3)
+	if o.Len() != 3 {
+		t.Fatalf("Len: got %d", o.Len())
+	}
+	o.Update(0, 10)
+	o.Update(2, 30)
+	o.Update(2, 31)
+	got := o.Scan()
+	if len(got) != 3 || got[0] != 10 || got[1] != 0 || got[2] != 31 {
+		t.Fatalf("Scan: got %v", got)
+	}
+	if o.Load(2) != 31 {
+		t.Fatalf("Load: got %d", o.Load(2))
+	}
+	if len(snapshot.New(0).Scan()) != 0 {
+		t.Fatalf("empty object returned components")
+	}
+}
+
+// TestObject_ScansAreComparable runs monotonic writers, one per component,
+// and checks that every pair of scans taken by any readers is ordered
+// component-wise, as scans taken at single instants must be.
+func TestObject_ScansAreComparable(t *testing.T) {
+	const (
+		components = 4
+		readers    = 3
+	)
+	iters := 3000
+	if testing.Short() {
+		iters = 300
+	}
+	o := snapshot.New(components)
+	var stop atomic.Bool
+	scans := make([][][]uint64, readers)
+	var rw sync.WaitGroup
+	rw.Add(readers)
+	for r := range readers {
+		go func() {
+			defer rw.Done()
+			for !stop.Load() && len(scans[r]) < 2000 {
+				scans[r] = append(scans[r], o.Scan())
+			}
+		}()
+	}
+	var ww sync.WaitGroup
+	ww.Add(components)
+	for i := range components {
+		go func() {
+			defer ww.Done()
+			for k := 1; k <= iters; k++ {
+				o.Update(i, uint64(k))
+			}
+		}()
+	}
+	ww.Wait()
+	stop.Store(true)
+	rw.Wait()
+
+	var all [][]uint64
+	for _, s := range scans {
+		all = append(all, s...)
+	}
+	all = append(all, o.Scan())
+	for a := range all {
+		for b := a + 1; b < len(all); b++ {
+			if !comparable(all[a], all[b]) {
+				t.Fatalf("scans %v and %v are not ordered", all[a], all[b])
+			}
+		}
+	}
+	for i, v := range all[len(all)-1] {
+		if v != uint64(iters) {
+			t.Fatalf("final component %d = %d, want %d", i, v, iters)
+		}
+	}
+}
+
+func comparable(x, y []uint64) bool {
+	le, ge := true, true
+	for i := range x {
+		le = le && x[i] <= y[i]
+		ge = ge && x[i] >= y[i]
+	}
+	return le || ge
+}
+
+// TestObject_OwnerWritesInOrder has one writer own two components and always
+// update component 0 before component 1; a scan must never show component 1
+// ahead of component 0 or more than one step behind it.
+func TestObject_OwnerWritesInOrder(t *testing.T) {
+	o := snapshot.New(2)
+	iters := 20000
+	if testing.Short() {
+		iters = 2000
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for k := uint64(1); k <= uint64(iters); k++ {
+			o.Update(0, k)
+			o.Update(1, k)
+		}
+	}()
+	for {
+		s := o.Scan()
+		if s[1] > s[0] || s[0] > s[1]+1 {
+			t.Fatalf("inconsistent scan %v", s)
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+	}
+}
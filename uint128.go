@@ -8,6 +8,7 @@ import (
 	"unsafe"
 
 	"code.hybscloud.com/dwcas/internal/arch"
+	"code.hybscloud.com/dwcas/internal/hook"
 )
 
 // Uint128 is a 16-byte value used with 128-bit compare-and-swap.
@@ -17,6 +18,9 @@ import (
 //	word 0: Lo
 //	word 1: Hi
 //
+// As an integer the value is Hi<<64 | Lo; the 16-byte forms produced by
+// [Uint128.Bytes16] and [Uint128.MarshalBinary] are big-endian, Hi first.
+//
 // The address of a *Uint128 used with these methods MUST be 16-byte aligned.
 //
 // Helpers:
@@ -41,6 +45,9 @@ type Uint128 struct {
 //   - On unsupported architectures, Relaxed panics.
 func (p *Uint128) Relaxed(old, new Uint128) (prev Uint128, swapped bool) {
 	checkAligned(p)
+	if prev, swapped, handled := hookCas128(p, old, new, hook.Relaxed); handled {
+		return prev, swapped
+	}
 	lo, hi, ok := arch.Cas128Relaxed((*uint64)(unsafe.Pointer(p)), old.Lo, old.Hi, new.Lo, new.Hi)
 	return Uint128{Lo: lo, Hi: hi}, ok
 }
@@ -58,6 +65,9 @@ func (p *Uint128) Relaxed(old, new Uint128) (prev Uint128, swapped bool) {
 //   - On unsupported architectures, Acquire panics.
 func (p *Uint128) Acquire(old, new Uint128) (prev Uint128, swapped bool) {
 	checkAligned(p)
+	if prev, swapped, handled := hookCas128(p, old, new, hook.Acquire); handled {
+		return prev, swapped
+	}
 	lo, hi, ok := arch.Cas128Acquire((*uint64)(unsafe.Pointer(p)), old.Lo, old.Hi, new.Lo, new.Hi)
 	return Uint128{Lo: lo, Hi: hi}, ok
 }
@@ -75,6 +85,9 @@ func (p *Uint128) Acquire(old, new Uint128) (prev Uint128, swapped bool) {
 //   - On unsupported architectures, Release panics.
 func (p *Uint128) Release(old, new Uint128) (prev Uint128, swapped bool) {
 	checkAligned(p)
+	if prev, swapped, handled := hookCas128(p, old, new, hook.Release); handled {
+		return prev, swapped
+	}
 	lo, hi, ok := arch.Cas128Release((*uint64)(unsafe.Pointer(p)), old.Lo, old.Hi, new.Lo, new.Hi)
 	return Uint128{Lo: lo, Hi: hi}, ok
 }
@@ -92,6 +105,27 @@ func (p *Uint128) Release(old, new Uint128) (prev Uint128, swapped bool) {
 //   - On unsupported architectures, AcqRel panics.
 func (p *Uint128) AcqRel(old, new Uint128) (prev Uint128, swapped bool) {
 	checkAligned(p)
+	if prev, swapped, handled := hookCas128(p, old, new, hook.AcqRel); handled {
+		return prev, swapped
+	}
 	lo, hi, ok := arch.Cas128AcqRel((*uint64)(unsafe.Pointer(p)), old.Lo, old.Hi, new.Lo, new.Hi)
 	return Uint128{Lo: lo, Hi: hi}, ok
 }
+
+// Load atomically reads the value at p with acquire ordering.
+//
+// Load is a relaxed compare-and-swap whose old and new values are equal,
+// followed by an acquire barrier. It never changes the value, but it costs as
+// much as any CAS: it takes the cache line for writing, so it faults on
+// read-only mappings and contends with every other access to p, including
+// other Loads.
+//
+// Contract:
+//   - p must be non-nil.
+//   - p must be 16-byte aligned.
+//   - On unsupported architectures, Load panics.
+func (p *Uint128) Load() Uint128 {
+	prev, _ := p.Relaxed(Uint128{}, Uint128{})
+	BarrierAcquire()
+	return prev
+}
@@ -0,0 +1,77 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dwcas
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+	"net/netip"
+)
+
+// The conversions in this file map between a Uint128, read as the unsigned
+// integer Hi<<64 | Lo, and other 128-bit representations. The byte forms are
+// big-endian unless the name ends in LE: byte 0 is the most significant byte
+// of Hi and byte 15 the least significant byte of Lo. This is network byte
+// order, which is how IPv6 addresses and RFC 9562 UUIDs are written, so a
+// value's Hi word holds the routing prefix or the UUID's time fields.
+//
+// Except for [Uint128.Big], the conversions do not allocate.
+
+// FromBig returns b as a Uint128. ok is false, and x is zero, if b is negative
+// or does not fit in 128 bits.
+func FromBig(b *big.Int) (x Uint128, ok bool) {
+	if b.Sign() < 0 || b.BitLen() > 128 {
+		return Uint128{}, false
+	}
+	for i, w := range b.Bits() {
+		// Words are 32 or 64 bits wide; place word i at bit i*UintSize.
+		shift := uint(i * bits.UintSize)
+		x = x.Or(Uint128{Lo: uint64(w)}.Lsh(shift))
+	}
+	return x, true
+}
+
+// Big returns x as a newly allocated [big.Int].
+func (x Uint128) Big() *big.Int {
+	b := x.Bytes16()
+	return new(big.Int).SetBytes(b[:])
+}
+
+// FromBytes16 returns the Uint128 whose big-endian encoding is b.
+func FromBytes16(b [16]byte) Uint128 {
+	return Uint128{Hi: binary.BigEndian.Uint64(b[:8]), Lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+// Bytes16 returns the big-endian encoding of x.
+func (x Uint128) Bytes16() (b [16]byte) {
+	x.PutBigEndian(b[:])
+	return b
+}
+
+// FromBytes16LE returns the Uint128 whose little-endian encoding is b.
+func FromBytes16LE(b [16]byte) Uint128 {
+	return Uint128{Lo: binary.LittleEndian.Uint64(b[:8]), Hi: binary.LittleEndian.Uint64(b[8:])}
+}
+
+// Bytes16LE returns the little-endian encoding of x.
+func (x Uint128) Bytes16LE() (b [16]byte) {
+	x.PutLittleEndian(b[:])
+	return b
+}
+
+// FromAddr returns the 16-byte form of a as a Uint128, as returned by
+// [netip.Addr.As16]: an IPv4 address becomes its IPv4-mapped IPv6 address, the
+// zone of an IPv6 address is dropped, and the zero Addr becomes zero.
+func FromAddr(a netip.Addr) Uint128 {
+	return FromBytes16(a.As16())
+}
+
+// Addr returns x as an IPv6 address. For a value produced from an IPv4 address
+// by [FromAddr], call [netip.Addr.Unmap] on the result to get the IPv4 address
+// back.
+func (x Uint128) Addr() netip.Addr {
+	return netip.AddrFrom16(x.Bytes16())
+}
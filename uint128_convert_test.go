@@ -0,0 +1,83 @@
+package dwcas_test
+
+import (
+	"math/big"
+	"net/netip"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+)
+
+func TestBig_RoundTrip(t *testing.T) {
+	for _, x := range mathOperands(200) {
+		b := x.Big()
+		if b.Cmp(toBig(x)) != 0 {
+			t.Fatalf("Big(%v): got %v", x, b)
+		}
+		if y, ok := dwcas.FromBig(b); !ok || y != x {
+			t.Fatalf("FromBig(%v): got (%v, %v)", b, y, ok)
+		}
+	}
+	for _, b := range []*big.Int{big.NewInt(-1), two128, new(big.Int).Lsh(two128, 1)} {
+		if x, ok := dwcas.FromBig(b); ok || x != (dwcas.Uint128{}) {
+			t.Fatalf("FromBig(%v): got (%v, %v), want (0, false)", b, x, ok)
+		}
+	}
+}
+
+func TestBytes16_Layout(t *testing.T) {
+	x := dwcas.Uint128{Hi: 0x0001020304050607, Lo: 0x08090a0b0c0d0e0f}
+	be := x.Bytes16()
+	le := x.Bytes16LE()
+	for i := 0; i < 16; i++ {
+		if be[i] != byte(i) || le[15-i] != byte(i) {
+			t.Fatalf("Bytes16 %x / Bytes16LE %x do not match the Lo/Hi layout", be, le)
+		}
+	}
+	for _, v := range mathOperands(100) {
+		if dwcas.FromBytes16(v.Bytes16()) != v || dwcas.FromBytes16LE(v.Bytes16LE()) != v {
+			t.Fatalf("Bytes16 round trip of %v failed", v)
+		}
+		if b := v.Bytes16(); b != bx16(v) {
+			t.Fatalf("Bytes16(%v): got %x, want %x", v, b, bx16(v))
+		}
+	}
+}
+
+func TestAddr_RoundTrip(t *testing.T) {
+	for _, s := range []string{"::", "::1", "2001:db8::1", "fe80::1", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"} {
+		a := netip.MustParseAddr(s)
+		x := dwcas.FromAddr(a)
+		if x.Addr() != a {
+			t.Fatalf("Addr(FromAddr(%v)): got %v", a, x.Addr())
+		}
+	}
+	if x := dwcas.FromAddr(netip.MustParseAddr("2001:db8::1")); x != (dwcas.Uint128{Hi: 0x20010db800000000, Lo: 1}) {
+		t.Fatalf("FromAddr(2001:db8::1): got %#x", x)
+	}
+	v4 := netip.MustParseAddr("192.0.2.1")
+	x := dwcas.FromAddr(v4)
+	if x != (dwcas.Uint128{Lo: 0x0000ffffc0000201}) || x.Addr().Unmap() != v4 {
+		t.Fatalf("FromAddr(%v): got %#x, Addr %v", v4, x, x.Addr())
+	}
+	if z := dwcas.FromAddr(netip.MustParseAddr("fe80::1%eth0")); z.Addr() != netip.MustParseAddr("fe80::1") {
+		t.Fatalf("zone not dropped: %v", z.Addr())
+	}
+}
+
+func TestConvert_NoAllocs(t *testing.T) {
+	b := toBig(dwcas.Uint128{Lo: 3, Hi: 5})
+	a := netip.MustParseAddr("2001:db8::1")
+	var sink dwcas.Uint128
+	allocs := testing.AllocsPerRun(100, func() {
+		x, _ := dwcas.FromBig(b)
+		x = dwcas.FromBytes16(x.Bytes16())
+		x = dwcas.FromBytes16LE(x.Bytes16LE())
+		sink = dwcas.FromAddr(x.Addr())
+		sink = sink.Xor(dwcas.FromAddr(a))
+	})
+	if allocs != 0 {
+		t.Fatalf("conversions allocated %v times per run", allocs)
+	}
+	_ = sink
+}
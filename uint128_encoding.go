@@ -0,0 +1,320 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dwcas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+)
+
+// The methods in this file format, parse and serialize a Uint128 as the
+// unsigned integer Hi<<64 | Lo. Text forms are decimal unless a base is
+// requested; the binary form is 16 bytes, big-endian (Hi first), so encoded
+// values sort in numeric order.
+
+// String returns x in decimal.
+func (x Uint128) String() string {
+	return string(x.appendBase(nil, 10, false))
+}
+
+// chunkPow is the largest power of each supported base that fits in a
+// uint64, with its exponent; formatting divides by it to peel off whole
+// 64-bit chunks of digits.
+var chunkPow = map[int]struct {
+	pow    uint64
+	digits int
+}{
+	2:  {1 << 63, 63},
+	8:  {1 << 63, 21},
+	10: {1e19, 19},
+	16: {1 << 60, 15},
+}
+
+// appendBase appends the digits of x in base 2, 8, 10 or 16, without prefix.
+func (x Uint128) appendBase(dst []byte, base int, upper bool) []byte {
+	if x.Hi == 0 {
+		return appendUint64(dst, x.Lo, base, upper)
+	}
+	c := chunkPow[base]
+	q, r := x.QuoRem(Uint128{Lo: c.pow})
+	dst = q.appendBase(dst, base, upper)
+	digits := appendUint64(nil, r.Lo, base, upper)
+	for i := len(digits); i < c.digits; i++ {
+		dst = append(dst, '0')
+	}
+	return append(dst, digits...)
+}
+
+func appendUint64(dst []byte, v uint64, base int, upper bool) []byte {
+	n := len(dst)
+	dst = strconv.AppendUint(dst, v, base)
+	if upper {
+		for i := n; i < len(dst); i++ {
+			if c := dst[i]; 'a' <= c && c <= 'f' {
+				dst[i] = c - 'a' + 'A'
+			}
+		}
+	}
+	return dst
+}
+
+// Format implements [fmt.Formatter]. It supports the verbs %d, %v and %s
+// (decimal), %x and %X (hexadecimal), %b (binary) and %o and %O (octal), with
+// the '#', '+', '-', ' ' and '0' flags, width and precision as for the
+// built-in unsigned integer types. Like them, x prints as %#x under %#v.
+func (x Uint128) Format(f fmt.State, verb rune) {
+	formatInteger(f, verb, "dwcas.Uint128", false, false, x)
+}
+
+// formatInteger implements Format for Uint128 and Int128: mag is the magnitude
+// and neg the sign of the value, signed whether its type is signed, and typ
+// the type name for bad-verb output. It follows the steps of fmt's integer
+// formatting: zero padding, then the prefix, then padding to the width.
+func formatInteger(f fmt.State, verb rune, typ string, signed, neg bool, mag Uint128) {
+	var base int
+	var upper bool
+	switch {
+	case verb == 'v' && f.Flag('#') && !signed:
+		// Go syntax, as for the built-in unsigned integers.
+		base = 16
+	case verb == 'd', verb == 'v', verb == 's':
+		base = 10
+	case verb == 'x':
+		base = 16
+	case verb == 'X':
+		base, upper = 16, true
+	case verb == 'b':
+		base = 2
+	case verb == 'o', verb == 'O':
+		base = 8
+	default:
+		sign := ""
+		if neg {
+			sign = "-"
+		}
+		fmt.Fprintf(f, "%%!%c(%s=%s%s)", verb, typ, sign, mag.String())
+		return
+	}
+
+	w, hasWidth := f.Width()
+	prec, hasPrec := f.Precision()
+	if hasPrec && prec == 0 && mag == (Uint128{}) {
+		// As for the built-in integers, zero with precision 0 prints
+		// nothing, not even a sign or prefix, but is still padded.
+		f.Write(bytes.Repeat([]byte{' '}, max(w, 0)))
+		return
+	}
+	sign := ""
+	switch {
+	case neg:
+		sign = "-"
+	case f.Flag('+') && verb != 'v':
+		// fmt reports %+v as the '+' flag, but the built-in integers
+		// print no plus sign for it.
+		sign = "+"
+	case f.Flag(' '):
+		sign = " "
+	}
+	if !hasPrec {
+		prec = 0
+		if hasWidth && f.Flag('0') && !f.Flag('-') {
+			// Zero padding fills the width but for the sign; the prefix
+			// is written in front of it.
+			prec = w - len(sign)
+		}
+	}
+
+	digits := mag.appendBase(nil, base, upper)
+	if n := prec - len(digits); n > 0 {
+		digits = append(bytes.Repeat([]byte{'0'}, n), digits...)
+	}
+	var prefix string
+	if f.Flag('#') {
+		switch {
+		case base == 2:
+			prefix = "0b"
+		case base == 8 && digits[0] != '0':
+			prefix = "0"
+		case base == 16 && upper:
+			prefix = "0X"
+		case base == 16:
+			prefix = "0x"
+		}
+	}
+	if verb == 'O' {
+		prefix = "0o" + prefix
+	}
+
+	out := make([]byte, 0, len(sign)+len(prefix)+len(digits))
+	out = append(out, sign...)
+	out = append(out, prefix...)
+	out = append(out, digits...)
+	if pad := w - len(out); hasWidth && pad > 0 {
+		spaces := bytes.Repeat([]byte{' '}, pad)
+		if f.Flag('-') {
+			out = append(out, spaces...)
+		} else {
+			out = append(spaces, out...)
+		}
+	}
+	f.Write(out)
+}
+
+// ParseUint128 parses s as a decimal number, or as a hexadecimal number when s
+// starts with "0x" or "0X". Errors are of type [*strconv.NumError], wrapping
+// [strconv.ErrSyntax] or [strconv.ErrRange].
+func ParseUint128(s string) (Uint128, error) {
+	const fn = "ParseUint128"
+	digits, base := s, uint64(10)
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		digits, base = s[2:], 16
+	}
+	if digits == "" {
+		return Uint128{}, &strconv.NumError{Func: fn, Num: s, Err: strconv.ErrSyntax}
+	}
+	var x Uint128
+	for i := 0; i < len(digits); i++ {
+		d, ok := digitVal(digits[i])
+		if !ok || d >= base {
+			return Uint128{}, &strconv.NumError{Func: fn, Num: s, Err: strconv.ErrSyntax}
+		}
+		// x = x*base + d, failing on overflow out of 128 bits.
+		hiHi, hiLo := bits.Mul64(x.Hi, base)
+		loHi, loLo := bits.Mul64(x.Lo, base)
+		hi, carry := bits.Add64(hiLo, loHi, 0)
+		if hiHi != 0 || carry != 0 {
+			return Uint128{}, &strconv.NumError{Func: fn, Num: s, Err: strconv.ErrRange}
+		}
+		lo, c := bits.Add64(loLo, d, 0)
+		hi, carry = bits.Add64(hi, 0, c)
+		if carry != 0 {
+			return Uint128{}, &strconv.NumError{Func: fn, Num: s, Err: strconv.ErrRange}
+		}
+		x = Uint128{Lo: lo, Hi: hi}
+	}
+	return x, nil
+}
+
+func digitVal(c byte) (uint64, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return uint64(c - '0'), true
+	case 'a' <= c && c <= 'f':
+		return uint64(c-'a') + 10, true
+	case 'A' <= c && c <= 'F':
+		return uint64(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// MarshalText implements [encoding.TextMarshaler]; the text is decimal.
+func (x Uint128) MarshalText() ([]byte, error) {
+	return x.AppendText(nil)
+}
+
+// AppendText implements [encoding.TextAppender].
+func (x Uint128) AppendText(b []byte) ([]byte, error) {
+	return x.appendBase(b, 10, false), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler]. It accepts the forms
+// accepted by [ParseUint128].
+//
+// UnmarshalText writes *x non-atomically; do not use it on a cell other
+// goroutines access concurrently.
+func (x *Uint128) UnmarshalText(text []byte) error {
+	v, err := ParseUint128(string(text))
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler]. The value is encoded as a decimal
+// string, since most JSON decoders cannot represent 128-bit numbers exactly.
+func (x Uint128) MarshalJSON() ([]byte, error) {
+	b := append(make([]byte, 0, 41), '"')
+	b = x.appendBase(b, 10, false)
+	return append(b, '"'), nil
+}
+
+// UnmarshalJSON implements [json.Unmarshaler]. It accepts a string in any form
+// accepted by [ParseUint128], or a bare non-negative integer. Like the
+// built-in types, it leaves *x unchanged for a JSON null.
+//
+// UnmarshalJSON writes *x non-atomically.
+func (x *Uint128) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	if err := x.UnmarshalText(data); err != nil {
+		return fmt.Errorf("dwcas: UnmarshalJSON: %w", err)
+	}
+	return nil
+}
+
+// errBinaryLength is returned by UnmarshalBinary for input that is not 16
+// bytes long.
+var errBinaryLength = errors.New("dwcas: UnmarshalBinary: want 16 bytes")
+
+// MarshalBinary implements [encoding.BinaryMarshaler]; the encoding is 16
+// bytes, big-endian.
+func (x Uint128) MarshalBinary() ([]byte, error) {
+	return x.AppendBigEndian(make([]byte, 0, 16)), nil
+}
+
+// AppendBinary implements [encoding.BinaryAppender]; it appends the 16-byte
+// big-endian encoding of x to b.
+func (x Uint128) AppendBinary(b []byte) ([]byte, error) {
+	return x.AppendBigEndian(b), nil
+}
+
+// UnmarshalBinary implements [encoding.BinaryUnmarshaler]. data must be the
+// 16-byte big-endian encoding.
+//
+// UnmarshalBinary writes *x non-atomically.
+func (x *Uint128) UnmarshalBinary(data []byte) error {
+	if len(data) != 16 {
+		return errBinaryLength
+	}
+	*x = Uint128{Hi: binary.BigEndian.Uint64(data), Lo: binary.BigEndian.Uint64(data[8:])}
+	return nil
+}
+
+// AppendBigEndian appends x to b as 16 bytes, most significant byte first.
+func (x Uint128) AppendBigEndian(b []byte) []byte {
+	b = binary.BigEndian.AppendUint64(b, x.Hi)
+	return binary.BigEndian.AppendUint64(b, x.Lo)
+}
+
+// AppendLittleEndian appends x to b as 16 bytes, least significant byte first.
+func (x Uint128) AppendLittleEndian(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint64(b, x.Lo)
+	return binary.LittleEndian.AppendUint64(b, x.Hi)
+}
+
+// PutBigEndian writes x into b[:16], most significant byte first. It panics if
+// len(b) < 16.
+func (x Uint128) PutBigEndian(b []byte) {
+	_ = b[15]
+	binary.BigEndian.PutUint64(b, x.Hi)
+	binary.BigEndian.PutUint64(b[8:], x.Lo)
+}
+
+// PutLittleEndian writes x into b[:16], least significant byte first. It panics
+// if len(b) < 16.
+func (x Uint128) PutLittleEndian(b []byte) {
+	_ = b[15]
+	binary.LittleEndian.PutUint64(b, x.Lo)
+	binary.LittleEndian.PutUint64(b[8:], x.Hi)
+}
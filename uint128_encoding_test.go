@@ -0,0 +1,188 @@
+package dwcas_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// integerFormats returns every combination of the integer verbs with the
+// flags, widths and precisions fmt accepts for them.
+func integerFormats() []string {
+	var formats []string
+	for _, verb := range "dvxXboO" {
+		for flags := 0; flags < 1<<5; flags++ {
+			var fl []byte
+			for i, c := range []byte("#+- 0") {
+				if flags&(1<<i) != 0 {
+					fl = append(fl, c)
+				}
+			}
+			for _, width := range []string{"", "1", "8", "25"} {
+				for _, prec := range []string{"", ".0", ".1", ".5", ".25"} {
+					formats = append(formats, "%"+string(fl)+width+prec+string(verb)+"|")
+				}
+			}
+		}
+	}
+	return formats
+}
+
+// TestFormat_FlagsMatchBuiltin checks Uint128 and Int128 against uint64 and
+// int64 for every format of integerFormats.
+func TestFormat_FlagsMatchBuiltin(t *testing.T) {
+	unsigned := []uint64{0, 1, 7, 8, 26, 255, 1 << 32, 1 << 63, math.MaxUint64}
+	signed := []int64{0, 1, -1, 8, -8, 26, -26, math.MinInt64, math.MaxInt64}
+	for _, f := range integerFormats() {
+		for _, v := range unsigned {
+			if got, want := fmt.Sprintf(f, dwcas.Uint128{Lo: v}), fmt.Sprintf(f, v); got != want {
+				t.Fatalf("Sprintf(%q, Uint128(%d)): got %q, want %q", f, v, got, want)
+			}
+		}
+		for _, v := range signed {
+			if got, want := fmt.Sprintf(f, dwcas.Int128FromInt64(v)), fmt.Sprintf(f, v); got != want {
+				t.Fatalf("Sprintf(%q, Int128(%d)): got %q, want %q", f, v, got, want)
+			}
+		}
+	}
+}
+
+func TestFormat_MatchesBuiltin(t *testing.T) {
+	formats := []string{
+		"%d", "%v", "%s", "%x", "%X", "%b", "%o", "%O",
+		"%#x", "%#X", "%#b", "%#o", "%+d", "% d",
+		"%50d", "%-50d|", "%050d", "%#050x", "%.45d", "%.0d", "%60.45x",
+	}
+	for _, x := range mathOperands(200) {
+		bx := toBig(x)
+		for _, f := range formats {
+			// Values that fit in 64 bits must format exactly like a
+			// uint64; math/big differs from the built-in types for %#o
+			// of zero. Like *big.Int, Uint128 also accepts %s.
+			var oracle any = bx
+			if x.Hi == 0 && f != "%s" {
+				oracle = x.Lo
+			} else if f == "%#050x" {
+				// math/big counts the prefix against the width.
+				continue
+			}
+			if got, want := fmt.Sprintf(f, x), fmt.Sprintf(f, oracle); got != want {
+				t.Fatalf("Sprintf(%q, %#x): got %q, want %q", f, bx, got, want)
+			}
+		}
+		if got, want := x.String(), bx.String(); got != want {
+			t.Fatalf("String: got %q, want %q", got, want)
+		}
+	}
+	if got := fmt.Sprintf("%q", dwcas.Uint128{Lo: 1}); got != "%!q(dwcas.Uint128=1)" {
+		t.Fatalf("unsupported verb: got %q", got)
+	}
+}
+
+func TestParseUint128(t *testing.T) {
+	for _, x := range mathOperands(200) {
+		for _, s := range []string{x.String(), fmt.Sprintf("%#x", x), fmt.Sprintf("%#X", x)} {
+			got, err := dwcas.ParseUint128(s)
+			if err != nil || got != x {
+				t.Fatalf("ParseUint128(%q): got (%v, %v), want %v", s, got, err, x)
+			}
+		}
+	}
+	for s, want := range map[string]error{
+		"":     strconv.ErrSyntax,
+		"0x":   strconv.ErrSyntax,
+		"-1":   strconv.ErrSyntax,
+		"12a":  strconv.ErrSyntax,
+		"0xfg": strconv.ErrSyntax,
+		" 1":   strconv.ErrSyntax,
+		"340282366920938463463374607431768211456": strconv.ErrRange, // 2^128
+		"0x100000000000000000000000000000000":     strconv.ErrRange,
+	} {
+		_, err := dwcas.ParseUint128(s)
+		var ne *strconv.NumError
+		if !errors.As(err, &ne) || !errors.Is(err, want) {
+			t.Errorf("ParseUint128(%q): got %v, want %v", s, err, want)
+		}
+	}
+	if x, err := dwcas.ParseUint128("340282366920938463463374607431768211455"); err != nil || x != (dwcas.Uint128{Lo: ^uint64(0), Hi: ^uint64(0)}) {
+		t.Fatalf("ParseUint128(max): got (%v, %v)", x, err)
+	}
+}
+
+func TestEncoding_RoundTrip(t *testing.T) {
+	type config struct {
+		ID dwcas.Uint128 `json:"id"`
+	}
+	for _, x := range mathOperands(100) {
+		text, _ := x.MarshalText()
+		var y dwcas.Uint128
+		if err := y.UnmarshalText(text); err != nil || y != x {
+			t.Fatalf("text round trip of %v: got (%v, %v)", x, y, err)
+		}
+
+		j, err := json.Marshal(config{ID: x})
+		if err != nil || string(j) != `{"id":"`+x.String()+`"}` {
+			t.Fatalf("json.Marshal(%v): got (%s, %v)", x, j, err)
+		}
+		var c config
+		if err := json.Unmarshal(j, &c); err != nil || c.ID != x {
+			t.Fatalf("json round trip of %v: got (%v, %v)", x, c.ID, err)
+		}
+		if err := json.Unmarshal([]byte(`{"id":`+x.String()+`}`), &c); err != nil || c.ID != x {
+			t.Fatalf("json bare number %v: got (%v, %v)", x, c.ID, err)
+		}
+		if err := json.Unmarshal([]byte(`{"id":null}`), &c); err != nil || c.ID != x {
+			t.Fatalf("json null over %v: got (%v, %v)", x, c.ID, err)
+		}
+
+		bin, _ := x.MarshalBinary()
+		want := bx16(x)
+		if !bytes.Equal(bin, want[:]) {
+			t.Fatalf("MarshalBinary(%v): got %x, want %x", x, bin, want)
+		}
+		if app, _ := x.AppendBinary([]byte{0xaa}); !bytes.Equal(app[1:], bin) || app[0] != 0xaa {
+			t.Fatalf("AppendBinary(%v): got %x", x, app)
+		}
+		y = dwcas.Uint128{}
+		if err := y.UnmarshalBinary(bin); err != nil || y != x {
+			t.Fatalf("binary round trip of %v: got (%v, %v)", x, y, err)
+		}
+
+		le := x.AppendLittleEndian(nil)
+		for i := range le {
+			if le[i] != bin[15-i] {
+				t.Fatalf("AppendLittleEndian(%v): got %x, want reverse of %x", x, le, bin)
+			}
+		}
+		var put [16]byte
+		x.PutBigEndian(put[:])
+		if !bytes.Equal(put[:], bin) {
+			t.Fatalf("PutBigEndian(%v): got %x", x, put)
+		}
+		x.PutLittleEndian(put[:])
+		if !bytes.Equal(put[:], le) {
+			t.Fatalf("PutLittleEndian(%v): got %x", x, put)
+		}
+	}
+
+	var y dwcas.Uint128
+	if err := y.UnmarshalBinary(make([]byte, 15)); err == nil {
+		t.Fatalf("UnmarshalBinary accepted 15 bytes")
+	}
+	if err := json.Unmarshal([]byte(`"-1"`), &y); err == nil {
+		t.Fatalf("UnmarshalJSON accepted -1")
+	}
+}
+
+// bx16 is the big-endian encoding of x computed through math/big.
+func bx16(x dwcas.Uint128) [16]byte {
+	var b [16]byte
+	toBig(x).FillBytes(b[:])
+	return b
+}
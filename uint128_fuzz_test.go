@@ -0,0 +1,212 @@
+package dwcas_test
+
+import (
+	"math/bits"
+	"testing"
+	"unsafe"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// opStream decodes fuzz input into operands; exhausted input reads as zeros.
+type opStream struct {
+	b []byte
+}
+
+func (s *opStream) byte() byte {
+	if len(s.b) == 0 {
+		return 0
+	}
+	v := s.b[0]
+	s.b = s.b[1:]
+	return v
+}
+
+func (s *opStream) u64() uint64 {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		v |= uint64(s.byte()) << (8 * i)
+	}
+	return v
+}
+
+func (s *opStream) u128() dwcas.Uint128 {
+	return dwcas.Uint128{Lo: s.u64(), Hi: s.u64()}
+}
+
+var casMethods = []struct {
+	name string
+	fn   func(p *dwcas.Uint128, old, new dwcas.Uint128) (dwcas.Uint128, bool)
+}{
+	{"Relaxed", (*dwcas.Uint128).Relaxed},
+	{"Acquire", (*dwcas.Uint128).Acquire},
+	{"Release", (*dwcas.Uint128).Release},
+	{"AcqRel", (*dwcas.Uint128).AcqRel},
+}
+
+// casAdd adds d to *p with a CAS loop, carrying from Lo into Hi and wrapping
+// modulo 2^128.
+func casAdd(p *dwcas.Uint128, d dwcas.Uint128) dwcas.Uint128 {
+	old := *p
+	for {
+		lo, c := bits.Add64(old.Lo, d.Lo, 0)
+		hi, _ := bits.Add64(old.Hi, d.Hi, c)
+		next := dwcas.Uint128{Lo: lo, Hi: hi}
+		prev, ok := p.AcqRel(old, next)
+		if ok {
+			return next
+		}
+		old = prev
+	}
+}
+
+// casSub subtracts d from *p with a CAS loop, borrowing from Hi and wrapping
+// modulo 2^128.
+func casSub(p *dwcas.Uint128, d dwcas.Uint128) dwcas.Uint128 {
+	old := *p
+	for {
+		lo, b := bits.Sub64(old.Lo, d.Lo, 0)
+		hi, _ := bits.Sub64(old.Hi, d.Hi, b)
+		next := dwcas.Uint128{Lo: lo, Hi: hi}
+		prev, ok := p.AcqRel(old, next)
+		if ok {
+			return next
+		}
+		old = prev
+	}
+}
+
+// refAdd and refSub are the reference model: 128-bit arithmetic on a pair of
+// words, one bit at a time.
+func refAdd(a, b dwcas.Uint128) dwcas.Uint128 {
+	var r dwcas.Uint128
+	carry := uint64(0)
+	for i := 0; i < 128; i++ {
+		x, y := bitAt(a, i), bitAt(b, i)
+		s := x ^ y ^ carry
+		carry = (x & y) | (carry & (x ^ y))
+		r = setBit(r, i, s)
+	}
+	return r
+}
+
+func refSub(a, b dwcas.Uint128) dwcas.Uint128 {
+	// a - b == a + ^b + 1 (mod 2^128)
+	return refAdd(refAdd(a, dwcas.Uint128{Lo: ^b.Lo, Hi: ^b.Hi}), dwcas.Uint128{Lo: 1})
+}
+
+func bitAt(v dwcas.Uint128, i int) uint64 {
+	if i < 64 {
+		return v.Lo >> i & 1
+	}
+	return v.Hi >> (i - 64) & 1
+}
+
+func setBit(v dwcas.Uint128, i int, b uint64) dwcas.Uint128 {
+	if i < 64 {
+		v.Lo |= b << i
+	} else {
+		v.Hi |= b << (i - 64)
+	}
+	return v
+}
+
+// FuzzUint128_SequentialModel drives CAS, load, store and arithmetic
+// operations on a Uint128 and checks each step against a sequential reference.
+func FuzzUint128_SequentialModel(f *testing.F) {
+	f.Add(false, uint8(0), []byte{0, 0, 1, 2, 3, 4})
+	f.Add(true, uint8(7), []byte{4, 5, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 6, 1})
+	f.Add(true, uint8(15), []byte{5, 1, 7, 2, 1, 1, 3, 0, 6, 0xff})
+	f.Add(false, uint8(3), []byte{2, 1, 9, 9, 9, 3, 0, 7, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+
+	f.Fuzz(func(t *testing.T, place bool, off uint8, ops []byte) {
+		var p *dwcas.Uint128
+		if place {
+			buf := make([]byte, 64)
+			o := int(off % 32)
+			_, p = dwcas.PlaceAlignedUint128(buf, o)
+		} else {
+			p = dwcas.New(0, 0)
+		}
+		if uintptr(unsafe.Pointer(p))%16 != 0 {
+			t.Fatalf("misaligned cell %p", p)
+		}
+
+		s := &opStream{b: ops}
+		ref := dwcas.Uint128{}
+		*p = ref
+		for step := 0; len(s.b) > 0 && step < 256; step++ {
+			op := s.byte() % 8
+			switch op {
+			case 0, 1, 2, 3:
+				m := casMethods[op]
+				expected := ref
+				if s.byte()&1 == 1 {
+					expected = s.u128()
+				}
+				desired := s.u128()
+				prev, swapped := m.fn(p, expected, desired)
+				if prev != ref {
+					t.Fatalf("step %d %s: prev=%v, want %v", step, m.name, prev, ref)
+				}
+				if swapped != (expected == ref) {
+					t.Fatalf("step %d %s: swapped=%v with expected=%v current=%v", step, m.name, swapped, expected, ref)
+				}
+				if swapped {
+					ref = desired
+				}
+			case 4:
+				if got := *p; got != ref {
+					t.Fatalf("step %d load: got %v, want %v", step, got, ref)
+				}
+			case 5:
+				v := s.u128()
+				*p = v
+				ref = v
+			case 6:
+				d := s.u128()
+				ref = refAdd(ref, d)
+				if got := casAdd(p, d); got != ref {
+					t.Fatalf("step %d add %v: got %v, want %v", step, d, got, ref)
+				}
+			case 7:
+				d := s.u128()
+				ref = refSub(ref, d)
+				if got := casSub(p, d); got != ref {
+					t.Fatalf("step %d sub %v: got %v, want %v", step, d, got, ref)
+				}
+			}
+			if *p != ref {
+				t.Fatalf("step %d op %d: memory %v, reference %v", step, op, *p, ref)
+			}
+		}
+	})
+}
+
+// FuzzPlaceAlignedUint128 checks the placement contract for arbitrary buffer
+// lengths and offsets.
+func FuzzPlaceAlignedUint128(f *testing.F) {
+	f.Add(uint16(31), int16(0))
+	f.Add(uint16(64), int16(33))
+	f.Add(uint16(30), int16(0))
+	f.Add(uint16(100), int16(-1))
+
+	f.Fuzz(func(t *testing.T, size uint16, off int16) {
+		buf := make([]byte, int(size)%512)
+		o := int(off)
+		can := dwcas.CanPlaceAlignedUint128(buf, o)
+		want := o >= 0 && o <= len(buf) && len(buf)-o >= 31
+		if can != want {
+			t.Fatalf("CanPlaceAlignedUint128(len=%d, off=%d) = %v, want %v", len(buf), o, can, want)
+		}
+		if !can {
+			return
+		}
+		n, p := dwcas.PlaceAlignedUint128(buf, o)
+		addr := uintptr(unsafe.Pointer(p))
+		start := uintptr(unsafe.Pointer(unsafe.SliceData(buf))) + uintptr(o)
+		if addr%16 != 0 || addr < start || n != int(addr-start)+16 || o+n > len(buf) {
+			t.Fatalf("bad placement: len=%d off=%d n=%d addr=%#x start=%#x", len(buf), o, n, addr, start)
+		}
+	})
+}
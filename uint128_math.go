@@ -0,0 +1,127 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dwcas
+
+import "math/bits"
+
+// The methods in this file treat a Uint128 as the unsigned integer Hi<<64 | Lo.
+// They operate on values, not on shared memory: none of them is atomic, and
+// they never touch the hook or arch layers. Arithmetic wraps modulo 2^128,
+// carrying from Lo into Hi exactly as a CAS-loop add built on [bits.Add64]
+// does, so a new value computed here can be passed straight to a CAS method.
+
+// Add returns x+y modulo 2^128.
+func (x Uint128) Add(y Uint128) Uint128 {
+	lo, c := bits.Add64(x.Lo, y.Lo, 0)
+	hi, _ := bits.Add64(x.Hi, y.Hi, c)
+	return Uint128{Lo: lo, Hi: hi}
+}
+
+// Sub returns x-y modulo 2^128.
+func (x Uint128) Sub(y Uint128) Uint128 {
+	lo, b := bits.Sub64(x.Lo, y.Lo, 0)
+	hi, _ := bits.Sub64(x.Hi, y.Hi, b)
+	return Uint128{Lo: lo, Hi: hi}
+}
+
+// Mul returns x*y modulo 2^128.
+func (x Uint128) Mul(y Uint128) Uint128 {
+	hi, lo := bits.Mul64(x.Lo, y.Lo)
+	hi += x.Hi*y.Lo + x.Lo*y.Hi
+	return Uint128{Lo: lo, Hi: hi}
+}
+
+// QuoRem returns the quotient x/y and remainder x%y.
+// QuoRem panics if y is zero.
+func (x Uint128) QuoRem(y Uint128) (q, r Uint128) {
+	if y.Hi == 0 {
+		if y.Lo == 0 {
+			panic("dwcas: QuoRem: division by zero")
+		}
+		// Two-step long division by a 64-bit divisor.
+		var rem uint64
+		q.Hi, rem = x.Hi/y.Lo, x.Hi%y.Lo
+		q.Lo, rem = bits.Div64(rem, x.Lo, y.Lo)
+		return q, Uint128{Lo: rem}
+	}
+	// The quotient fits in 64 bits. Estimate it from the divisor normalized
+	// so that its top bit is set; the estimate is at most one too large
+	// after the decrement and at most one too small before the fix-up.
+	n := uint(bits.LeadingZeros64(y.Hi))
+	y1 := y.Lsh(n).Hi
+	x1 := x.Rsh(1)
+	tq, _ := bits.Div64(x1.Hi, x1.Lo, y1)
+	tq >>= 63 - n
+	if tq != 0 {
+		tq--
+	}
+	q = Uint128{Lo: tq}
+	r = x.Sub(y.Mul(q))
+	if r.Cmp(y) >= 0 {
+		q = q.Add(Uint128{Lo: 1})
+		r = r.Sub(y)
+	}
+	return q, r
+}
+
+// Cmp compares x and y and returns -1, 0 or +1 when x < y, x == y or x > y.
+func (x Uint128) Cmp(y Uint128) int {
+	switch {
+	case x.Hi < y.Hi:
+		return -1
+	case x.Hi > y.Hi:
+		return 1
+	case x.Lo < y.Lo:
+		return -1
+	case x.Lo > y.Lo:
+		return 1
+	}
+	return 0
+}
+
+// Lsh returns x<<n. Shifts of 128 or more return zero.
+func (x Uint128) Lsh(n uint) Uint128 {
+	if n >= 64 {
+		return Uint128{Hi: x.Lo << (n - 64)}
+	}
+	return Uint128{Lo: x.Lo << n, Hi: x.Hi<<n | x.Lo>>(64-n)}
+}
+
+// Rsh returns x>>n. Shifts of 128 or more return zero.
+func (x Uint128) Rsh(n uint) Uint128 {
+	if n >= 64 {
+		return Uint128{Lo: x.Hi >> (n - 64)}
+	}
+	return Uint128{Lo: x.Lo>>n | x.Hi<<(64-n), Hi: x.Hi >> n}
+}
+
+// And returns x&y.
+func (x Uint128) And(y Uint128) Uint128 {
+	return Uint128{Lo: x.Lo & y.Lo, Hi: x.Hi & y.Hi}
+}
+
+// Or returns x|y.
+func (x Uint128) Or(y Uint128) Uint128 {
+	return Uint128{Lo: x.Lo | y.Lo, Hi: x.Hi | y.Hi}
+}
+
+// Xor returns x^y.
+func (x Uint128) Xor(y Uint128) Uint128 {
+	return Uint128{Lo: x.Lo ^ y.Lo, Hi: x.Hi ^ y.Hi}
+}
+
+// LeadingZeros returns the number of leading zero bits in x; it is 128 for
+// x == 0.
+func (x Uint128) LeadingZeros() int {
+	if x.Hi != 0 {
+		return bits.LeadingZeros64(x.Hi)
+	}
+	return 64 + bits.LeadingZeros64(x.Lo)
+}
+
+// OnesCount returns the number of one bits in x.
+func (x Uint128) OnesCount() int {
+	return bits.OnesCount64(x.Lo) + bits.OnesCount64(x.Hi)
+}
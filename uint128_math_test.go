@@ -0,0 +1,127 @@
+package dwcas_test
+
+import (
+	"math/big"
+	"math/rand/v2"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+)
+
+var two128 = new(big.Int).Lsh(big.NewInt(1), 128)
+
+func toBig(x dwcas.Uint128) *big.Int {
+	b := new(big.Int).SetUint64(x.Hi)
+	b.Lsh(b, 64)
+	return b.Or(b, new(big.Int).SetUint64(x.Lo))
+}
+
+// fromBig reduces b modulo 2^128.
+func fromBig(b *big.Int) dwcas.Uint128 {
+	m := new(big.Int).Mod(b, two128)
+	lo := new(big.Int).And(m, new(big.Int).SetUint64(^uint64(0))).Uint64()
+	return dwcas.Uint128{Lo: lo, Hi: m.Rsh(m, 64).Uint64()}
+}
+
+// mathOperands returns the boundary values of each half in every combination
+// followed by random values.
+func mathOperands(n int) []dwcas.Uint128 {
+	edges := []uint64{0, 1, 2, 3, 1 << 31, 1<<32 - 1, 1 << 32, 1<<63 - 1, 1 << 63, 1<<63 + 1, ^uint64(0) - 1, ^uint64(0)}
+	var vs []dwcas.Uint128
+	for _, hi := range edges {
+		for _, lo := range edges {
+			vs = append(vs, dwcas.Uint128{Lo: lo, Hi: hi})
+		}
+	}
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < n; i++ {
+		// Vary the magnitude so that both short and long divisors occur.
+		v := dwcas.Uint128{Lo: r.Uint64(), Hi: r.Uint64()}
+		vs = append(vs, v.Rsh(uint(r.IntN(128))))
+	}
+	return vs
+}
+
+func TestMath_Binary(t *testing.T) {
+	n := 200
+	if testing.Short() {
+		n = 40
+	}
+	vs := mathOperands(n)
+	for _, x := range vs {
+		bx := toBig(x)
+		for _, y := range vs {
+			by := toBig(y)
+			check := func(op string, got dwcas.Uint128, want *big.Int) {
+				t.Helper()
+				if w := fromBig(want); got != w {
+					t.Fatalf("%#x %s %#x: got %#x, want %#x", bx, op, by, toBig(got), toBig(w))
+				}
+			}
+			check("+", x.Add(y), new(big.Int).Add(bx, by))
+			check("-", x.Sub(y), new(big.Int).Sub(bx, by))
+			check("*", x.Mul(y), new(big.Int).Mul(bx, by))
+			check("&", x.And(y), new(big.Int).And(bx, by))
+			check("|", x.Or(y), new(big.Int).Or(bx, by))
+			check("^", x.Xor(y), new(big.Int).Xor(bx, by))
+			if got, want := x.Cmp(y), bx.Cmp(by); got != want {
+				t.Fatalf("Cmp(%#x, %#x): got %d, want %d", bx, by, got, want)
+			}
+			if y == (dwcas.Uint128{}) {
+				continue
+			}
+			q, r := x.QuoRem(y)
+			wq, wr := new(big.Int).QuoRem(bx, by, new(big.Int))
+			check("/", q, wq)
+			check("%", r, wr)
+		}
+	}
+}
+
+func TestMath_Unary(t *testing.T) {
+	for _, x := range mathOperands(1000) {
+		bx := toBig(x)
+		for n := uint(0); n <= 130; n++ {
+			if got, want := x.Lsh(n), fromBig(new(big.Int).Lsh(bx, n)); got != want {
+				t.Fatalf("%#x << %d: got %#x, want %#x", bx, n, toBig(got), toBig(want))
+			}
+			if got, want := x.Rsh(n), fromBig(new(big.Int).Rsh(bx, n)); got != want {
+				t.Fatalf("%#x >> %d: got %#x, want %#x", bx, n, toBig(got), toBig(want))
+			}
+		}
+		if got, want := x.LeadingZeros(), 128-bx.BitLen(); got != want {
+			t.Fatalf("LeadingZeros(%#x): got %d, want %d", bx, got, want)
+		}
+		ones := 0
+		for i := 0; i < 128; i++ {
+			ones += int(bx.Bit(i))
+		}
+		if got := x.OnesCount(); got != ones {
+			t.Fatalf("OnesCount(%#x): got %d, want %d", bx, got, ones)
+		}
+	}
+}
+
+func TestMath_AddMatchesCASLoop(t *testing.T) {
+	for _, x := range mathOperands(50) {
+		for _, y := range mathOperands(0) {
+			p := dwcas.New(x.Lo, x.Hi)
+			if got, want := casAdd(p, y), x.Add(y); got != want {
+				t.Fatalf("casAdd(%#x, %#x) = %#x, Add = %#x", toBig(x), toBig(y), toBig(got), toBig(want))
+			}
+			p = dwcas.New(x.Lo, x.Hi)
+			if got, want := casSub(p, y), x.Sub(y); got != want {
+				t.Fatalf("casSub(%#x, %#x) = %#x, Sub = %#x", toBig(x), toBig(y), toBig(got), toBig(want))
+			}
+		}
+	}
+}
+
+func TestQuoRem_ZeroPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("QuoRem by zero did not panic")
+		}
+	}()
+	dwcas.Uint128{Lo: 1}.QuoRem(dwcas.Uint128{})
+}
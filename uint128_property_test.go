@@ -0,0 +1,100 @@
+package dwcas_test
+
+import (
+	"sync"
+	"testing"
+
+	"code.hybscloud.com/dwcas"
+)
+
+// witness maps a sequence number to a Uint128 whose halves determine each
+// other, so a torn read of two different writes is detected.
+func witness(seq uint64) dwcas.Uint128 {
+	return dwcas.Uint128{Lo: seq, Hi: seq*0x9e3779b97f4a7c15 ^ 0xd1b54a32d192ed03}
+}
+
+func isWitness(v dwcas.Uint128) bool {
+	return v == witness(v.Lo)
+}
+
+func TestCAS_NoTornReads(t *testing.T) {
+	const (
+		writers = 4
+		readers = 4
+	)
+	iters := 20000
+	if testing.Short() {
+		iters = 2000
+	}
+
+	allocs := []struct {
+		name  string
+		alloc func() *dwcas.Uint128
+	}{
+		{"New", func() *dwcas.Uint128 { return dwcas.New(0, 0) }},
+		{"PlaceAlignedUint128", func() *dwcas.Uint128 {
+			buf := make([]byte, 96)
+			_, p := dwcas.PlaceAlignedUint128(buf, 9)
+			return p
+		}},
+	}
+
+	for _, a := range allocs {
+		for _, m := range casMethods {
+			t.Run(a.name+"/"+m.name, func(t *testing.T) {
+				p := a.alloc()
+				*p = witness(0)
+
+				var wg sync.WaitGroup
+				errs := make(chan dwcas.Uint128, readers+writers)
+				for w := 0; w < writers; w++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						old := witness(0)
+						for i := 1; i <= iters; i++ {
+							next := witness(uint64(w+1)<<32 | uint64(i))
+							for {
+								prev, ok := m.fn(p, old, next)
+								if ok {
+									old = next
+									break
+								}
+								if !isWitness(prev) {
+									errs <- prev
+									return
+								}
+								old = prev
+							}
+						}
+					}()
+				}
+				for r := 0; r < readers; r++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						guess := witness(0)
+						for i := 0; i < iters; i++ {
+							// A CAS whose old and new values are equal is an
+							// atomic snapshot whether or not it swaps.
+							prev, _ := m.fn(p, guess, guess)
+							if !isWitness(prev) {
+								errs <- prev
+								return
+							}
+							guess = prev
+						}
+					}()
+				}
+				wg.Wait()
+				close(errs)
+				for v := range errs {
+					t.Fatalf("observed a value that was never written: (%#x,%#x)", v.Lo, v.Hi)
+				}
+				if final := *p; !isWitness(final) {
+					t.Fatalf("final value was never written: (%#x,%#x)", final.Lo, final.Hi)
+				}
+			})
+		}
+	}
+}
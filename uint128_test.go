@@ -140,3 +140,40 @@ func TestCAS_Contention_Toggle(t *testing.T) {
 		t.Fatalf("contention test timed out")
 	}
 }
+
+func TestLoad(t *testing.T) {
+	p := dwcas.New(0, 0)
+	if got := p.Load(); got != (dwcas.Uint128{}) {
+		t.Fatalf("Load of zero: got (%d,%d)", got.Lo, got.Hi)
+	}
+	p.Lo, p.Hi = 7, 9
+	if got := p.Load(); got != (dwcas.Uint128{Lo: 7, Hi: 9}) {
+		t.Fatalf("Load: got (%d,%d)", got.Lo, got.Hi)
+	}
+	if p.Lo != 7 || p.Hi != 9 {
+		t.Fatalf("Load changed the value: got (%d,%d)", p.Lo, p.Hi)
+	}
+}
+
+func TestNewSlice_Alignment(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 17, 64} {
+		s := dwcas.NewSlice(n)
+		if len(s) != n {
+			t.Fatalf("NewSlice(%d): len=%d", n, len(s))
+		}
+		for i := range s {
+			if uintptr(unsafe.Pointer(&s[i]))%16 != 0 {
+				t.Fatalf("NewSlice(%d): element %d misaligned: %p", n, i, &s[i])
+			}
+			if s[i] != (dwcas.Uint128{}) {
+				t.Fatalf("NewSlice(%d): element %d not zero", n, i)
+			}
+		}
+		for i := range s {
+			if _, ok := s[i].AcqRel(dwcas.Uint128{}, dwcas.Uint128{Lo: uint64(i), Hi: 1}); !ok {
+				t.Fatalf("CAS on element %d failed", i)
+			}
+		}
+	}
+	mustPanic(t, func() { dwcas.NewSlice(-1) })
+}
@@ -0,0 +1,192 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dwcas
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// UUID is an RFC 9562 universally unique identifier in its 16-byte network
+// order form.
+type UUID [16]byte
+
+// Uint128 returns u as a Uint128 (big-endian, see [FromBytes16]): Hi holds the
+// first eight bytes, which include the version and, for version 7, the
+// timestamp.
+func (u UUID) Uint128() Uint128 {
+	return FromBytes16(u)
+}
+
+// Version returns the version field of u.
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// String returns u in the canonical 8-4-4-4-12 lowercase hexadecimal form.
+func (u UUID) String() string {
+	return string(u.appendString(make([]byte, 0, 36)))
+}
+
+func (u UUID) appendString(b []byte) []byte {
+	b = hex.AppendEncode(b, u[0:4])
+	b = append(b, '-')
+	b = hex.AppendEncode(b, u[4:6])
+	b = append(b, '-')
+	b = hex.AppendEncode(b, u[6:8])
+	b = append(b, '-')
+	b = hex.AppendEncode(b, u[8:10])
+	b = append(b, '-')
+	return hex.AppendEncode(b, u[10:16])
+}
+
+// MarshalText implements [encoding.TextMarshaler] with the canonical form.
+func (u UUID) MarshalText() ([]byte, error) {
+	return u.appendString(make([]byte, 0, 36)), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler]; it accepts the forms
+// accepted by [ParseUUID].
+func (u *UUID) UnmarshalText(text []byte) error {
+	v, err := ParseUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// ParseUUID parses the canonical 8-4-4-4-12 hexadecimal form of a UUID, in
+// either case, optionally enclosed in braces or prefixed with "urn:uuid:".
+func ParseUUID(s string) (UUID, error) {
+	t := s
+	switch {
+	case len(t) == 45 && t[:9] == "urn:uuid:":
+		t = t[9:]
+	case len(t) == 38 && t[0] == '{' && t[37] == '}':
+		t = t[1:37]
+	}
+	var u UUID
+	if len(t) != 36 || t[8] != '-' || t[13] != '-' || t[18] != '-' || t[23] != '-' {
+		return UUID{}, fmt.Errorf("dwcas: ParseUUID: invalid UUID %q", s)
+	}
+	j := 0
+	for i := 0; i < 36; i += 2 {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			i--
+			continue
+		}
+		hi, ok1 := digitVal(t[i])
+		lo, ok2 := digitVal(t[i+1])
+		if !ok1 || !ok2 {
+			return UUID{}, fmt.Errorf("dwcas: ParseUUID: invalid UUID %q", s)
+		}
+		u[j] = byte(hi<<4 | lo)
+		j++
+	}
+	return u, nil
+}
+
+// NewV4 returns a random (version 4) UUID read from crypto/rand.
+func NewV4() UUID {
+	var u UUID
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
+
+// lastV7 is the first eight bytes of the most recent version 7 UUID generated
+// in this process, as a big-endian integer.
+var lastV7 atomic.Uint64
+
+// NewV7 returns a time-ordered (version 7) UUID: a 48-bit Unix millisecond
+// timestamp, followed by 12 bits of sub-millisecond time (RFC 9562 section
+// 6.2, method 3) and 62 random bits.
+//
+// UUIDs generated by NewV7 within one process are strictly increasing, even
+// when the clock does not advance between calls or steps backwards.
+func NewV7() UUID {
+	var u UUID
+	rand.Read(u[8:])
+	now := time.Now().UnixNano()
+	ms := uint64(now / 1e6)
+	frac := uint64(now%1e6) << 12 / 1e6
+	head := ms<<16 | 0x7000 | frac
+	for {
+		last := lastV7.Load()
+		if head <= last {
+			// Same or earlier tick: step past the previous UUID. Skip
+			// from a full 12-bit fraction into the next millisecond so
+			// the version nibble stays intact.
+			head = last + 1
+			if head&0xfff == 0 {
+				head += 0x10000 - 0x1000
+			}
+		}
+		if lastV7.CompareAndSwap(last, head) {
+			break
+		}
+	}
+	binary.BigEndian.PutUint64(u[:8], head)
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
+
+// AtomicUUID is a UUID stored in a 16-byte aligned [Uint128] cell, so it can
+// be loaded, replaced and compared-and-swapped as a single 128-bit atomic.
+//
+// The zero value holds the nil UUID and is ready to use. An AtomicUUID must not
+// be copied after first use.
+//
+// Load has acquire ordering; Store, Swap and CompareAndSwap have acq_rel
+// ordering on success.
+type AtomicUUID struct {
+	c inlineCell
+}
+
+// Load returns the current UUID.
+func (a *AtomicUUID) Load() UUID {
+	return a.c.ptr().Load().Bytes16()
+}
+
+// Store sets the UUID to u.
+func (a *AtomicUUID) Store(u UUID) {
+	a.Swap(u)
+}
+
+// Swap sets the UUID to u and returns the previous UUID.
+func (a *AtomicUUID) Swap(u UUID) (old UUID) {
+	return a.c.swap(u.Uint128()).Bytes16()
+}
+
+// CompareAndSwap sets the UUID to new if it is old and reports whether it did.
+func (a *AtomicUUID) CompareAndSwap(old, new UUID) (swapped bool) {
+	_, swapped = a.c.ptr().AcqRel(old.Uint128(), new.Uint128())
+	return swapped
+}
+
+// StoreNewV4 stores a new [NewV4] UUID and returns it.
+func (a *AtomicUUID) StoreNewV4() UUID {
+	u := NewV4()
+	a.Store(u)
+	return u
+}
+
+// StoreNewV7 stores a new [NewV7] UUID and returns it.
+func (a *AtomicUUID) StoreNewV7() UUID {
+	u := NewV7()
+	a.Store(u)
+	return u
+}
+
+// String returns the current UUID in canonical form.
+func (a *AtomicUUID) String() string {
+	return a.Load().String()
+}
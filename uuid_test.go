@@ -0,0 +1,127 @@
+package dwcas_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/dwcas"
+)
+
+func TestUUID_ParseFormat(t *testing.T) {
+	const s = "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"
+	want := dwcas.UUID{0xf8, 0x1d, 0x4f, 0xae, 0x7d, 0xec, 0x11, 0xd0, 0xa7, 0x65, 0x00, 0xa0, 0xc9, 0x1e, 0x6b, 0xf6}
+	for _, in := range []string{s, "F81D4FAE-7DEC-11D0-A765-00A0C91E6BF6", "{" + s + "}", "urn:uuid:" + s} {
+		u, err := dwcas.ParseUUID(in)
+		if err != nil || u != want {
+			t.Fatalf("ParseUUID(%q): got (%v, %v)", in, u, err)
+		}
+	}
+	if want.String() != s || want.Version() != 1 {
+		t.Fatalf("String/Version: got %q, %d", want.String(), want.Version())
+	}
+	if want.Uint128() != (dwcas.Uint128{Hi: 0xf81d4fae7dec11d0, Lo: 0xa76500a0c91e6bf6}) {
+		t.Fatalf("Uint128: got %#x", want.Uint128())
+	}
+	for _, bad := range []string{"", s[:35], s + "0", "f81d4fae-7dec-11d0-a765_00a0c91e6bf6", "g81d4fae-7dec-11d0-a765-00a0c91e6bf6", "{" + s, "urn:uid:" + s} {
+		if _, err := dwcas.ParseUUID(bad); err == nil {
+			t.Errorf("ParseUUID(%q) succeeded", bad)
+		}
+	}
+	text, _ := want.MarshalText()
+	var u dwcas.UUID
+	if err := u.UnmarshalText(text); err != nil || u != want || !bytes.Equal(text, []byte(s)) {
+		t.Fatalf("text round trip: got (%s, %v, %v)", text, u, err)
+	}
+}
+
+func TestNewV4(t *testing.T) {
+	a, b := dwcas.NewV4(), dwcas.NewV4()
+	if a == b {
+		t.Fatalf("two v4 UUIDs are equal: %v", a)
+	}
+	for _, u := range []dwcas.UUID{a, b} {
+		if u.Version() != 4 || u[8]&0xc0 != 0x80 {
+			t.Fatalf("%v: bad version or variant", u)
+		}
+	}
+}
+
+func TestNewV7_Monotonic(t *testing.T) {
+	start := time.Now().UnixMilli()
+	prev := dwcas.NewV7()
+	for i := 0; i < 100000; i++ {
+		u := dwcas.NewV7()
+		if u.Version() != 7 || u[8]&0xc0 != 0x80 {
+			t.Fatalf("%v: bad version or variant", u)
+		}
+		if bytes.Compare(prev[:8], u[:8]) >= 0 {
+			t.Fatalf("v7 UUIDs not increasing: %v then %v", prev, u)
+		}
+		prev = u
+	}
+	ms := int64(prev.Uint128().Hi >> 16)
+	if ms < start || ms > time.Now().UnixMilli()+1000 {
+		t.Fatalf("v7 timestamp %d outside [%d, now]", ms, start)
+	}
+}
+
+func TestAtomicUUID(t *testing.T) {
+	var a dwcas.AtomicUUID
+	if a.Load() != (dwcas.UUID{}) {
+		t.Fatalf("zero AtomicUUID: got %v", a.Load())
+	}
+	u := a.StoreNewV4()
+	if a.Load() != u || a.String() != u.String() {
+		t.Fatalf("Load after StoreNewV4: got %v, want %v", a.Load(), u)
+	}
+	v := dwcas.NewV7()
+	if a.CompareAndSwap(v, v) {
+		t.Fatalf("CompareAndSwap succeeded with a stale old value")
+	}
+	if !a.CompareAndSwap(u, v) || a.Load() != v {
+		t.Fatalf("CompareAndSwap failed")
+	}
+	if old := a.Swap(u); old != v || a.Load() != u {
+		t.Fatalf("Swap: got %v, want %v", old, v)
+	}
+	if w := a.StoreNewV7(); a.Load() != w || w.Version() != 7 {
+		t.Fatalf("StoreNewV7: got %v", a.Load())
+	}
+
+	// The cell stays usable whatever the AtomicUUID's own alignment.
+	var pad struct {
+		_ uint64
+		a dwcas.AtomicUUID
+	}
+	pad.a.Store(u)
+	if pad.a.Load() != u {
+		t.Fatalf("offset AtomicUUID: got %v", pad.a.Load())
+	}
+}
+
+func TestAtomicUUID_Takeover(t *testing.T) {
+	const workers = 8
+	var a dwcas.AtomicUUID
+	a.Store(dwcas.NewV4())
+	var wins sync.Map
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				cur := a.Load()
+				next := dwcas.NewV7()
+				if a.CompareAndSwap(cur, next) {
+					if _, dup := wins.LoadOrStore(cur, true); dup {
+						t.Errorf("session %v taken over twice", cur)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}